@@ -0,0 +1,245 @@
+package bootstrap
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/robfig/cron/v3"
+	"github.com/thanvuc/go-core-lib/cache"
+	"github.com/thanvuc/go-core-lib/cronjob"
+	"github.com/thanvuc/go-core-lib/eventbus"
+	"github.com/thanvuc/go-core-lib/log"
+	"github.com/thanvuc/go-core-lib/mongolib"
+	"github.com/thanvuc/go-core-lib/storage"
+	"go.uber.org/zap"
+)
+
+// ErrNotConfigured is returned by an accessor when the configuration section of the component is missing.
+var ErrNotConfigured = errors.New("component is not configured")
+
+// App owns the core clients of a service.
+// Components are constructed on first access and only when their configuration section is present.
+type App struct {
+	cfg    Config
+	logger log.Logger
+
+	mu       sync.Mutex
+	redis    *cache.RedisCache
+	mongo    *mongolib.MongoConnector
+	rabbitMQ *eventbus.RabbitMQConnector
+	storage  *storage.R2Client
+	cron     *cronjob.CronManager
+}
+
+// New creates an App from cfg. Only the logger is built eagerly.
+func New(cfg Config) *App {
+	return &App{
+		cfg:    cfg,
+		logger: log.NewLogger(cfg.Log),
+	}
+}
+
+// Load reads the environment configuration file located in path and creates an App from it.
+func Load(path string) (*App, error) {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg), nil
+}
+
+// Config returns the configuration the App was created with.
+func (a *App) Config() Config {
+	return a.cfg
+}
+
+// Logger returns the service logger.
+func (a *App) Logger() log.Logger {
+	return a.logger
+}
+
+// Redis returns the Redis cache, connecting and pinging it on first use.
+func (a *App) Redis() (*cache.RedisCache, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.redisLocked()
+}
+
+func (a *App) redisLocked() (*cache.RedisCache, error) {
+	if a.redis != nil {
+		return a.redis, nil
+	}
+	if a.cfg.Redis == nil {
+		return nil, fmt.Errorf("redis: %w", ErrNotConfigured)
+	}
+
+	r, err := cache.New(*a.cfg.Redis, cache.WithLogger(a.logger))
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	if err := r.PingContext(context.Background()); err != nil {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		r.Close(&wg)
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+
+	a.logger.Info("Redis cache initialized", "", zap.String("mode", string(cmp.Or(a.cfg.Redis.Mode, cache.ModeStandalone))))
+	a.redis = r
+	return r, nil
+}
+
+// Mongo returns the MongoDB connector, connecting on first use.
+func (a *App) Mongo(ctx context.Context) (*mongolib.MongoConnector, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.mongo != nil {
+		return a.mongo, nil
+	}
+	if a.cfg.Mongo == nil {
+		return nil, fmt.Errorf("mongo: %w", ErrNotConfigured)
+	}
+
+	connector, err := mongolib.NewMongoConnector(ctx, *a.cfg.Mongo)
+	if err != nil {
+		return nil, fmt.Errorf("mongo: %w", err)
+	}
+
+	a.logger.Info("MongoDB connector initialized", "", zap.String("database", a.cfg.Mongo.Database))
+	a.mongo = connector
+	return connector, nil
+}
+
+// EventBus returns the RabbitMQ connector, connecting on first use.
+func (a *App) EventBus() (*eventbus.RabbitMQConnector, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.rabbitMQ != nil {
+		return a.rabbitMQ, nil
+	}
+	if a.cfg.RabbitMQ == nil {
+		return nil, fmt.Errorf("rabbitmq: %w", ErrNotConfigured)
+	}
+
+	connector, err := eventbus.NewConnector(a.cfg.RabbitMQ.URI, a.logger)
+	if err != nil {
+		return nil, fmt.Errorf("rabbitmq: %w", err)
+	}
+
+	a.rabbitMQ = connector
+	return connector, nil
+}
+
+// Storage returns the R2 storage client, creating it on first use.
+func (a *App) Storage() (*storage.R2Client, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.storage != nil {
+		return a.storage, nil
+	}
+	if a.cfg.Storage == nil {
+		return nil, fmt.Errorf("storage: %w", ErrNotConfigured)
+	}
+
+	client, err := storage.NewClient(*a.cfg.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("storage: %w", err)
+	}
+
+	a.logger.Info("Storage client initialized", "", zap.String("bucket", a.cfg.Storage.Bucket))
+	a.storage = client
+	return client, nil
+}
+
+// Cron returns the cron manager.
+func (a *App) Cron() (*cronjob.CronManager, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.cronLocked()
+}
+
+func (a *App) cronLocked() (*cronjob.CronManager, error) {
+	if a.cron != nil {
+		return a.cron, nil
+	}
+	if a.cfg.Cron == nil || !a.cfg.Cron.Enabled {
+		return nil, fmt.Errorf("cron: %w", ErrNotConfigured)
+	}
+
+	a.cron = cronjob.NewCronManager()
+	return a.cron, nil
+}
+
+// NewCronScheduler creates a scheduler that locks through the App's Redis cache and logs
+// through the App's logger, and registers it with the cron manager.
+func (a *App) NewCronScheduler(name string, opts ...cron.Option) (cronjob.CronScheduler, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	manager, err := a.cronLocked()
+	if err != nil {
+		return nil, err
+	}
+	redisCache, err := a.redisLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	scheduler := cronjob.NewCronScheduler(cache.NewRedisStore(redisCache), name, a.logger, opts...)
+	manager.AddScheduler(scheduler)
+	return scheduler, nil
+}
+
+// Shutdown stops the cron schedulers and closes every component that was constructed,
+// in the reverse order of their dependencies, then flushes the logger.
+func (a *App) Shutdown(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var errs []error
+	var wg sync.WaitGroup
+
+	if a.cron != nil {
+		wg.Add(1)
+		a.cron.Shutdown(&wg)
+		a.cron = nil
+	}
+
+	if a.rabbitMQ != nil {
+		if err := a.rabbitMQ.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("rabbitmq: %w", err))
+		}
+		a.rabbitMQ = nil
+	}
+
+	if a.mongo != nil {
+		if err := a.mongo.GracefulClose(ctx, nil); err != nil {
+			errs = append(errs, fmt.Errorf("mongo: %w", err))
+		}
+		a.mongo = nil
+	}
+
+	if a.redis != nil {
+		wg.Add(1)
+		if err := a.redis.Close(&wg); err != nil {
+			errs = append(errs, fmt.Errorf("redis: %w", err))
+		}
+		a.redis = nil
+	}
+
+	a.storage = nil
+
+	wg.Wait()
+
+	wg.Add(1)
+	// Syncing stdout returns an error on some platforms, it is not worth reporting.
+	_ = a.logger.Sync(&wg)
+
+	return errors.Join(errs...)
+}
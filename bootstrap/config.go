@@ -0,0 +1,38 @@
+package bootstrap
+
+import (
+	"github.com/thanvuc/go-core-lib/cache"
+	"github.com/thanvuc/go-core-lib/config"
+	"github.com/thanvuc/go-core-lib/log"
+	"github.com/thanvuc/go-core-lib/mongolib"
+	"github.com/thanvuc/go-core-lib/storage"
+)
+
+// Config is the standard service configuration.
+// Every section except Log is optional; a component is only built when its section is present.
+type Config struct {
+	Log      log.Config
+	Redis    *cache.Config
+	Mongo    *mongolib.MongoConnectorConfig
+	RabbitMQ *RabbitMQConfig
+	Storage  *storage.Config
+	Cron     *CronConfig
+}
+
+type RabbitMQConfig struct {
+	URI string
+}
+
+type CronConfig struct {
+	// Enabled turns on the cron manager. Schedulers additionally require the Redis section.
+	Enabled bool
+}
+
+// LoadConfig loads the environment configuration file located in path into a Config.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	if err := config.LoadConfig(&cfg, path); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
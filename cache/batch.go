@@ -0,0 +1,219 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Item is a value to store under a key with its own TTL.
+type Item[T any] struct {
+	Key   string
+	Value T
+	TTL   time.Duration
+}
+
+// Result is the typed outcome of the operation on a single key of a batch or pipeline.
+// For reads Hit reports whether the key existed; Err holds the error of that key only.
+type Result[T any] struct {
+	Key   string
+	Value T
+	Hit   bool
+	Err   error
+}
+
+// MGet reads keys in a single round trip and returns one result per key, in order.
+// The returned error is only set when the round trip itself failed.
+func MGet[T any](ctx context.Context, r *RedisCache, keys ...string) ([]Result[T], error) {
+	p := NewPipeline(r)
+	pending := make([]*Result[T], len(keys))
+	for i, key := range keys {
+		pending[i] = PipeGet[T](p, key)
+	}
+
+	if err := p.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result[T], len(keys))
+	for i, res := range pending {
+		results[i] = *res
+	}
+	return results, nil
+}
+
+// MSet stores items in a single round trip, each with its own TTL.
+func MSet[T any](ctx context.Context, r *RedisCache, items ...Item[T]) error {
+	p := NewPipeline(r)
+	pending := make([]*Result[T], len(items))
+	for i, item := range items {
+		pending[i] = PipeSet(p, item.Key, item.Value, item.TTL)
+	}
+
+	if err := p.Exec(ctx); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, res := range pending {
+		if res.Err != nil {
+			errs = append(errs, res.Err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// MDelete removes keys in a single round trip and returns how many existed.
+func MDelete(ctx context.Context, r *RedisCache, keys ...string) (int, error) {
+	p := NewPipeline(r)
+	pending := make([]*Result[bool], len(keys))
+	for i, key := range keys {
+		pending[i] = PipeDelete(p, key)
+	}
+
+	if err := p.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	var errs []error
+	for _, res := range pending {
+		if res.Err != nil {
+			errs = append(errs, res.Err)
+		}
+		if res.Value {
+			deleted++
+		}
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// Pipeline queues typed cache operations and sends them to Redis in one round trip.
+// Results are filled in when Exec returns.
+type Pipeline struct {
+	r       *RedisCache
+	pipe    redis.Pipeliner
+	resolve []func(ctx context.Context)
+	written []string
+}
+
+// NewPipeline creates a pipeline whose operations are sent together but run independently.
+func NewPipeline(r *RedisCache) *Pipeline {
+	return &Pipeline{r: r, pipe: r.Universal.Pipeline()}
+}
+
+// NewTxPipeline creates a pipeline whose operations run atomically in a MULTI/EXEC transaction.
+func NewTxPipeline(r *RedisCache) *Pipeline {
+	return &Pipeline{r: r, pipe: r.Universal.TxPipeline()}
+}
+
+// PipeGet queues a read of key.
+func PipeGet[T any](p *Pipeline, key string) *Result[T] {
+	res := &Result[T]{Key: key}
+	redisKey := p.r.Key(key)
+	cmd := p.pipe.Get(context.Background(), redisKey)
+
+	p.resolve = append(p.resolve, func(ctx context.Context) {
+		data, err := cmd.Bytes()
+		if errors.Is(err, ErrCircuitOpen) {
+			res.Value, err = fallbackValue[T](p.r, redisKey)
+			res.Hit = err == nil
+			return
+		}
+		if err == redis.Nil {
+			return
+		}
+		if err != nil {
+			res.Err = err
+			return
+		}
+		if err := p.r.decode(data, &res.Value); err != nil {
+			var zero T
+			res.Value = zero
+			if err := p.r.decodeMiss(ctx, redisKey, data, err); err != redis.Nil {
+				res.Err = err
+			}
+			return
+		}
+		res.Hit = true
+	})
+	return res
+}
+
+// PipeSet queues a write of value under key.
+func PipeSet[T any](p *Pipeline, key string, value T, expiration time.Duration) *Result[T] {
+	res := &Result[T]{Key: key, Value: value}
+
+	data, err := p.r.encode(value)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	redisKey := p.r.Key(key)
+	cmd := p.pipe.Set(context.Background(), redisKey, data, p.r.jitter(expiration))
+	p.written = append(p.written, redisKey)
+	p.resolve = append(p.resolve, func(context.Context) {
+		res.Err = cmd.Err()
+	})
+	return res
+}
+
+// PipeDelete queues the removal of key. The result value reports whether the key existed.
+func PipeDelete(p *Pipeline, key string) *Result[bool] {
+	res := &Result[bool]{Key: key}
+	redisKey := p.r.Key(key)
+	cmd := p.pipe.Del(context.Background(), redisKey)
+
+	p.written = append(p.written, redisKey)
+	p.resolve = append(p.resolve, func(context.Context) {
+		n, err := cmd.Result()
+		res.Value, res.Err = n > 0, err
+	})
+	return res
+}
+
+// PipeExpire queues a TTL change of key. The result value reports whether the key existed.
+func PipeExpire(p *Pipeline, key string, expiration time.Duration) *Result[bool] {
+	res := &Result[bool]{Key: key}
+	redisKey := p.r.Key(key)
+	cmd := p.pipe.Expire(context.Background(), redisKey, expiration)
+
+	p.written = append(p.written, redisKey)
+	p.resolve = append(p.resolve, func(context.Context) {
+		res.Value, res.Err = cmd.Result()
+	})
+	return res
+}
+
+// Exec sends the queued operations and fills in their results.
+// It only returns an error when the round trip failed; errors of single operations, and
+// ErrCircuitOpen while the circuit breaker is open, are reported in their results.
+func (p *Pipeline) Exec(ctx context.Context) error {
+	if len(p.resolve) == 0 {
+		return nil
+	}
+
+	writeCtx, cancel := p.r.writeContext(ctx)
+	defer cancel()
+
+	_, err := p.pipe.Exec(writeCtx)
+	for _, resolve := range p.resolve {
+		resolve(ctx)
+	}
+	p.resolve = nil
+
+	var replyErr redis.Error
+	if err != nil && err != redis.Nil && !errors.As(err, &replyErr) && !errors.Is(err, ErrCircuitOpen) {
+		return err
+	}
+
+	if len(p.written) > 0 {
+		written := p.written
+		p.written = nil
+		p.r.invalidateNear(ctx, written...)
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/thanvuc/go-core-lib/log"
+	"go.uber.org/zap"
+)
+
+// ErrCircuitOpen is returned by commands rejected without reaching Redis because the circuit breaker is open.
+// Reads through GetContext, MGet and GetOrLoad report a miss instead.
+var ErrCircuitOpen = errors.New("cache: circuit breaker open")
+
+type CircuitState int32
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+type CircuitBreakerConfig struct {
+	Enabled bool
+	// Window is the period over which the error rate is computed.
+	Window time.Duration
+	// MinRequests is the number of commands needed in a window before the circuit can open.
+	MinRequests int
+	// ErrorRate is the fraction of failed commands in a window that opens the circuit.
+	// Connection errors and timeouts are failures; redis.Nil and error replies are not.
+	ErrorRate float64
+	// SlowCallThreshold counts commands slower than it as failures, zero disables it.
+	// Blocking commands such as BLPOP are never slow.
+	SlowCallThreshold time.Duration
+	// OpenTimeout is how long the circuit stays open before probing Redis again.
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of probe commands that must succeed to close the circuit.
+	HalfOpenRequests int
+	// FallbackEntries keeps up to that many recently read and written values in memory,
+	// served by GetContext and GetOrLoad while the circuit is open. Zero disables the fallback.
+	// The fallback is not invalidated by other replicas, it only holds last known values.
+	FallbackEntries int
+	// FallbackTTL bounds how long a value is kept in the fallback.
+	FallbackTTL time.Duration
+}
+
+// withCircuitBreakerDefaults fills in missing config fields with sane defaults.
+func withCircuitBreakerDefaults(cfg *CircuitBreakerConfig) {
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 20
+	}
+	if cfg.ErrorRate <= 0 || cfg.ErrorRate > 1 {
+		cfg.ErrorRate = 0.5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 5 * time.Second
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 3
+	}
+	if cfg.FallbackTTL <= 0 {
+		cfg.FallbackTTL = time.Minute
+	}
+}
+
+// blockingCommands wait on purpose and are never counted as slow.
+var blockingCommands = map[string]bool{
+	"blpop": true, "brpop": true, "brpoplpush": true, "blmove": true, "blmpop": true,
+	"bzpopmin": true, "bzpopmax": true, "bzmpop": true, "xread": true, "xreadgroup": true, "wait": true,
+}
+
+// circuitBreaker is a go-redis hook rejecting commands while Redis looks unavailable.
+type circuitBreaker struct {
+	cfg    CircuitBreakerConfig
+	logger log.Logger
+
+	mu          sync.Mutex
+	state       CircuitState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probes      int
+	successes   int
+
+	// metrics are the metrics hooks added after the breaker, which never see the commands it rejects.
+	metrics []*metricsHook
+}
+
+func newCircuitBreaker(cfg CircuitBreakerConfig, logger log.Logger) *circuitBreaker {
+	return &circuitBreaker{cfg: cfg, logger: logger, windowStart: time.Now()}
+}
+
+func (b *circuitBreaker) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// admittedKey marks the context of a command let through by the breaker. The commands go-redis
+// sends to initialize a new connection run with that context and are part of the same call.
+type admittedKey struct{}
+
+func (b *circuitBreaker) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if ctx.Value(admittedKey{}) != nil {
+			return next(ctx, cmd)
+		}
+		probe, err := b.allow()
+		if err != nil {
+			b.rejected(ctx, err, cmd)
+			return err
+		}
+		start := time.Now()
+		err = next(context.WithValue(ctx, admittedKey{}, true), cmd)
+		b.record(probe, err, !blockingCommands[cmd.Name()] && b.slow(time.Since(start)))
+		return err
+	}
+}
+
+func (b *circuitBreaker) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if ctx.Value(admittedKey{}) != nil {
+			return next(ctx, cmds)
+		}
+		probe, err := b.allow()
+		if err != nil {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			b.rejected(ctx, err, cmds...)
+			return err
+		}
+		start := time.Now()
+		err = next(context.WithValue(ctx, admittedKey{}, true), cmds)
+		b.record(probe, err, b.slow(time.Since(start)))
+		return err
+	}
+}
+
+// observe reports the commands rejected by the breaker to h.
+func (b *circuitBreaker) observe(h *metricsHook) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.metrics = append(b.metrics, h)
+}
+
+// rejected counts cmds as failed in the metrics.
+func (b *circuitBreaker) rejected(ctx context.Context, err error, cmds ...redis.Cmder) {
+	b.mu.Lock()
+	metrics := b.metrics
+	b.mu.Unlock()
+
+	for _, h := range metrics {
+		for _, cmd := range cmds {
+			h.countResults(ctx, cmd, err)
+		}
+	}
+}
+
+func (b *circuitBreaker) slow(d time.Duration) bool {
+	return b.cfg.SlowCallThreshold > 0 && d > b.cfg.SlowCallThreshold
+}
+
+// allow reports whether a command may be sent, and whether it is a half-open probe.
+func (b *circuitBreaker) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if time.Since(b.openedAt) < b.cfg.OpenTimeout {
+			return false, ErrCircuitOpen
+		}
+		b.setStateLocked(CircuitHalfOpen)
+		fallthrough
+	case CircuitHalfOpen:
+		if b.probes >= b.cfg.HalfOpenRequests {
+			return false, ErrCircuitOpen
+		}
+		b.probes++
+		return true, nil
+	}
+	return false, nil
+}
+
+func (b *circuitBreaker) record(probe bool, err error, slow bool) {
+	var reply redis.Error
+	if errors.Is(err, context.Canceled) {
+		// The caller gave up, this says nothing about Redis.
+		if probe {
+			b.mu.Lock()
+			b.probes--
+			b.mu.Unlock()
+		}
+		return
+	}
+	failed := slow || (err != nil && err != redis.Nil && !errors.As(err, &reply))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		if b.state != CircuitHalfOpen {
+			return
+		}
+		if failed {
+			b.setStateLocked(CircuitOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenRequests {
+			b.setStateLocked(CircuitClosed)
+		}
+		return
+	}
+	if b.state != CircuitClosed {
+		return
+	}
+
+	if now := time.Now(); now.Sub(b.windowStart) >= b.cfg.Window {
+		b.windowStart = now
+		b.requests, b.failures = 0, 0
+	}
+	b.requests++
+	if failed {
+		b.failures++
+	}
+	if b.requests >= b.cfg.MinRequests && float64(b.failures) >= b.cfg.ErrorRate*float64(b.requests) {
+		b.setStateLocked(CircuitOpen)
+	}
+}
+
+func (b *circuitBreaker) setStateLocked(state CircuitState) {
+	prev := b.state
+	b.state = state
+	b.probes, b.successes = 0, 0
+
+	switch state {
+	case CircuitOpen:
+		b.openedAt = time.Now()
+		if b.logger != nil {
+			b.logger.Warn("Redis circuit breaker opened", "",
+				zap.String("from", prev.String()),
+				zap.Int("requests", b.requests),
+				zap.Int("failures", b.failures),
+				zap.Duration("open_timeout", b.cfg.OpenTimeout))
+		}
+	case CircuitHalfOpen:
+		if b.logger != nil {
+			b.logger.Info("Redis circuit breaker half-open, probing", "")
+		}
+	case CircuitClosed:
+		b.windowStart = time.Now()
+		b.requests, b.failures = 0, 0
+		if b.logger != nil {
+			b.logger.Info("Redis circuit breaker closed", "")
+		}
+	}
+}
+
+func (b *circuitBreaker) currentState() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// CircuitState returns the state of the circuit breaker, always CircuitClosed when it is disabled.
+func (r *RedisCache) CircuitState() CircuitState {
+	if r.breaker == nil {
+		return CircuitClosed
+	}
+	return r.breaker.currentState()
+}
+
+// rememberFallback keeps value in the fallback, if any, for the circuit breaker to serve while open.
+func (r *RedisCache) rememberFallback(key string, value any, expiration time.Duration) {
+	if r.fallback == nil {
+		return
+	}
+	ttl := r.cfg.CircuitBreaker.FallbackTTL
+	if expiration > 0 && expiration < ttl {
+		ttl = expiration
+	}
+	r.fallback.set(key, value, ttl)
+}
+
+func (r *RedisCache) forgetFallback(keys ...string) {
+	if r.fallback == nil {
+		return
+	}
+	for _, key := range keys {
+		r.fallback.delete(key)
+	}
+}
+
+// fallbackValue returns the value of a Redis key kept in the fallback, or redis.Nil.
+func fallbackValue[T any](r *RedisCache, key string) (T, error) {
+	var zero T
+	if r.fallback == nil {
+		return zero, redis.Nil
+	}
+	if v, ok := r.fallback.get(key); ok {
+		if value, ok := v.(T); ok {
+			return value, nil
+		}
+	}
+	return zero, redis.Nil
+}
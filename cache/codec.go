@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec serializes the values stored by the typed cache functions.
+type Codec interface {
+	// ID identifies the codec in the header of stored values so that values written with
+	// one codec stay readable after switching to another one.
+	// IDs 1 to 4 are used by the built-in codecs, custom codecs may use 5 to 7.
+	ID() uint8
+	Name() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var ErrNotProtoMessage = errors.New("cache: value does not implement proto.Message")
+
+const (
+	codecIDJSON uint8 = iota + 1
+	codecIDMsgpack
+	codecIDGob
+	codecIDProtobuf
+)
+
+var (
+	JSONCodec     Codec = jsonCodec{}
+	MsgpackCodec  Codec = msgpackCodec{}
+	GobCodec      Codec = gobCodec{}
+	ProtobufCodec Codec = protobufCodec{}
+)
+
+var builtinCodecs = []Codec{JSONCodec, MsgpackCodec, GobCodec, ProtobufCodec}
+
+// codecByName resolves the codec configured in Config.Codec.
+func codecByName(name string) (Codec, error) {
+	if name == "" {
+		return JSONCodec, nil
+	}
+	for _, c := range builtinCodecs {
+		if c.Name() == name {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("cache: unknown codec %q", name)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ID() uint8    { return codecIDJSON }
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) ID() uint8    { return codecIDMsgpack }
+func (msgpackCodec) Name() string { return "msgpack" }
+
+func (msgpackCodec) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+type gobCodec struct{}
+
+func (gobCodec) ID() uint8    { return codecIDGob }
+func (gobCodec) Name() string { return "gob" }
+
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// protobufCodec works with values that implement proto.Message, either directly
+// (T is *pb.Message) or through a pointer (T is pb.Message).
+type protobufCodec struct{}
+
+func (protobufCodec) ID() uint8    { return codecIDProtobuf }
+func (protobufCodec) Name() string { return "protobuf" }
+
+func (protobufCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, ErrNotProtoMessage
+	}
+	return proto.Marshal(m)
+}
+
+func (protobufCodec) Unmarshal(data []byte, v any) error {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, m)
+	}
+
+	// v is a pointer to a message pointer, allocate the message when needed.
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Pointer {
+		return ErrNotProtoMessage
+	}
+	elem := rv.Elem()
+	if elem.IsNil() {
+		elem.Set(reflect.New(elem.Type().Elem()))
+	}
+	m, ok := elem.Interface().(proto.Message)
+	if !ok {
+		return ErrNotProtoMessage
+	}
+	return proto.Unmarshal(data, m)
+}
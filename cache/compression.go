@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+type Compression string
+
+const (
+	CompressionNone   Compression = "none"
+	CompressionGzip   Compression = "gzip"
+	CompressionSnappy Compression = "snappy"
+	CompressionZstd   Compression = "zstd"
+)
+
+// compressionID identifies the compression algorithm in the header of stored values.
+func (c Compression) id() (uint8, error) {
+	switch c {
+	case "", CompressionNone:
+		return 0, nil
+	case CompressionGzip:
+		return 1, nil
+	case CompressionSnappy:
+		return 2, nil
+	case CompressionZstd:
+		return 3, nil
+	default:
+		return 0, fmt.Errorf("cache: unknown compression %q", string(c))
+	}
+}
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+// zstdCodecs lazily creates the shared zstd encoder and decoder, both are safe for concurrent use.
+func zstdCodecs() (*zstd.Encoder, *zstd.Decoder, error) {
+	zstdOnce.Do(func() {
+		zstdEncoder, zstdErr = zstd.NewWriter(nil)
+		if zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil)
+	})
+	return zstdEncoder, zstdDecoder, zstdErr
+}
+
+func compress(id uint8, data []byte) ([]byte, error) {
+	switch id {
+	case 0:
+		return data, nil
+	case 1:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case 2:
+		return snappy.Encode(nil, data), nil
+	case 3:
+		enc, _, err := zstdCodecs()
+		if err != nil {
+			return nil, err
+		}
+		return enc.EncodeAll(data, nil), nil
+	default:
+		return nil, fmt.Errorf("cache: unknown compression id %d", id)
+	}
+}
+
+func decompress(id uint8, data []byte) ([]byte, error) {
+	switch id {
+	case 0:
+		return data, nil
+	case 1:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case 2:
+		return snappy.Decode(nil, data)
+	case 3:
+		_, dec, err := zstdCodecs()
+		if err != nil {
+			return nil, err
+		}
+		return dec.DecodeAll(data, nil)
+	default:
+		return nil, fmt.Errorf("cache: unknown compression id %d", id)
+	}
+}
@@ -1,9 +1,113 @@
 package cache
 
+import "time"
+
+// Mode is the Redis deployment topology.
+type Mode string
+
+const (
+	ModeStandalone Mode = "standalone"
+	ModeSentinel   Mode = "sentinel"
+	ModeCluster    Mode = "cluster"
+)
+
 type Config struct {
-	Addr     string
+	// Mode selects the topology, standalone by default.
+	Mode Mode
+	// Addr is the address of a standalone server.
+	Addr string
+	// Addrs are the seed nodes of a cluster.
+	Addrs []string
+	// MasterName is the name of the master monitored by the sentinels.
+	MasterName string
+	// SentinelAddrs are the addresses of the sentinels.
+	SentinelAddrs    []string
+	SentinelUsername string
+	SentinelPassword string
+
+	// Username and Password authenticate with Redis 6 ACLs; leave Username empty for the default user.
+	Username string
 	Password string
+	// DB is the database to select. It is ignored in cluster mode.
 	DB       int
 	PoolSize int
 	MinIdle  int
+
+	// TLS enables TLS on the connections to Redis, and to the sentinels in sentinel mode.
+	TLS TLSConfig
+
+	// DialTimeout, ReadTimeout and WriteTimeout are the socket timeouts; zero uses the go-redis defaults.
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// MaxRetries is the number of retries of a failed command, zero uses the go-redis default
+	// and a negative value disables retries. Retries wait between MinRetryBackoff and MaxRetryBackoff.
+	MaxRetries      int
+	MinRetryBackoff time.Duration
+	MaxRetryBackoff time.Duration
+
+	// Namespace is prepended to every key so that services sharing a database do not collide.
+	Namespace string
+	// SchemaVersion is prepended to the keys of cached values, after the namespace, when positive.
+	// Bumping it makes every value written by the previous layout unreachable at once; locks,
+	// rate limits and other coordination state only use the namespace and are not affected.
+	SchemaVersion int
+
+	// TTLJitter shortens the expiration of written values by a random fraction of up to TTLJitter,
+	// for example 0.1 for up to 10%, so that keys written together do not expire together.
+	// It applies to SetContext, MSet, pipelines, SetWithTags and GetOrLoad, not to SetNXContext.
+	TTLJitter float64
+
+	// CircuitBreaker stops sending commands to Redis while it looks unavailable.
+	CircuitBreaker CircuitBreakerConfig
+
+	// ReadOperationTimeout bounds read operations (Get, Exists...) whose context has no deadline.
+	// Zero uses the default, a negative value disables the timeout.
+	ReadOperationTimeout time.Duration
+	// WriteOperationTimeout bounds write operations (Set, Delete, Expire...) whose context has no deadline.
+	// Zero uses the default, a negative value disables the timeout.
+	WriteOperationTimeout time.Duration
+
+	// Codec is the name of the codec used to serialize values: json (default), msgpack, gob or protobuf.
+	// It is ignored when a codec is passed with WithCodec.
+	Codec string
+	// Compression compresses serialized values of at least CompressionThreshold bytes.
+	Compression Compression
+	// CompressionThreshold is the minimum size in bytes of a value to be compressed.
+	CompressionThreshold int
+}
+
+type TLSConfig struct {
+	Enabled bool
+	// CAFile is a PEM file of the certificate authorities trusted to sign the server certificate.
+	// The system pool is used when empty.
+	CAFile string
+	// CertFile and KeyFile are the PEM client certificate and key for mutual TLS.
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the host name used to verify the server certificate.
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// withDefaults fills in missing config fields with sane defaults.
+func withDefaults(cfg *Config) {
+	if cfg.Mode == "" {
+		cfg.Mode = ModeStandalone
+	}
+	if cfg.ReadOperationTimeout == 0 {
+		cfg.ReadOperationTimeout = 3 * time.Second
+	}
+	if cfg.WriteOperationTimeout == 0 {
+		cfg.WriteOperationTimeout = 3 * time.Second
+	}
+	if cfg.Compression == "" {
+		cfg.Compression = CompressionNone
+	}
+	if cfg.CompressionThreshold <= 0 {
+		cfg.CompressionThreshold = 1024
+	}
+	if cfg.CircuitBreaker.Enabled {
+		withCircuitBreakerDefaults(&cfg.CircuitBreaker)
+	}
 }
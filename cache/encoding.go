@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Stored values start with a header byte:
+//
+//	bit 7     always set, distinguishes encoded values from legacy raw JSON
+//	bits 6-4  codec ID, 0 for the tombstones of negative caching which have no payload
+//	bits 3-2  compression ID
+//	bit 1     type version flag, followed by the uvarint version of the value type
+//	bit 0     soft expiry flag, followed by the uvarint Unix time in milliseconds after which the value is stale
+//
+// Values whose first byte has bit 7 cleared were written before codecs existed and are raw JSON.
+const (
+	headerMarker     = 0x80
+	headerCodecShift = 4
+	headerCodecMask  = 0x07
+	headerCompShift  = 2
+	headerCompMask   = 0x03
+	headerVersionBit = 0x02
+	headerSoftBit    = 0x01
+)
+
+var ErrCorruptValue = errors.New("cache: corrupt cached value")
+
+// errTombstone is returned by decode for the marker stored by negative caching.
+var errTombstone = errors.New("cache: tombstone")
+
+// tombstone is the value stored by negative caching for keys whose loader found nothing.
+var tombstone = []byte{headerMarker}
+
+// Encode serializes v the way values are stored in Redis, with the configured codec and compression.
+// It lets packages built on the cache store values in other data structures.
+func (r *RedisCache) Encode(v any) ([]byte, error) {
+	return r.encode(v)
+}
+
+// Decode deserializes data produced by Encode into v.
+func (r *RedisCache) Decode(data []byte, v any) error {
+	return r.decode(data, v)
+}
+
+// encode serializes v with the configured codec and compresses it when it is large enough.
+func (r *RedisCache) encode(v any) ([]byte, error) {
+	return r.encodeEntry(v, time.Time{})
+}
+
+// encodeEntry is encode with the time after which the value is stale, if not zero.
+func (r *RedisCache) encodeEntry(v any, softExpiry time.Time) ([]byte, error) {
+	payload, err := r.codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	compID := uint8(0)
+	if r.compressionID != 0 && len(payload) >= r.cfg.CompressionThreshold {
+		compressed, err := compress(r.compressionID, payload)
+		if err != nil {
+			return nil, err
+		}
+		// Only keep the compressed form when it actually saves space.
+		if len(compressed) < len(payload) {
+			payload = compressed
+			compID = r.compressionID
+		}
+	}
+
+	header := headerMarker | r.codec.ID()<<headerCodecShift | compID<<headerCompShift
+	version := r.typeVersion(reflect.TypeOf(v))
+	if version > 0 {
+		header |= headerVersionBit
+	}
+	if !softExpiry.IsZero() {
+		header |= headerSoftBit
+	}
+
+	data := make([]byte, 0, len(payload)+1+2*binary.MaxVarintLen64)
+	data = append(data, header)
+	if version > 0 {
+		data = binary.AppendUvarint(data, version)
+	}
+	if !softExpiry.IsZero() {
+		data = binary.AppendUvarint(data, uint64(softExpiry.UnixMilli()))
+	}
+	return append(data, payload...), nil
+}
+
+// decode deserializes data written by encode with any known codec, or legacy raw JSON, into v.
+// It returns a *staleVersionError when data was written with another version of the type of v,
+// and errTombstone for the marker of negative caching.
+func (r *RedisCache) decode(data []byte, v any) error {
+	_, err := r.decodeEntry(data, v)
+	return err
+}
+
+// decodeEntry is decode also returning the time after which the value is stale, zero when it has none.
+func (r *RedisCache) decodeEntry(data []byte, v any) (time.Time, error) {
+	var softExpiry time.Time
+	if len(data) == 0 {
+		return softExpiry, ErrCorruptValue
+	}
+
+	header := data[0]
+	payload := data[1:]
+	if header&headerMarker == 0 {
+		if err := r.checkVersion(0, v); err != nil {
+			return softExpiry, err
+		}
+		return softExpiry, json.Unmarshal(data, v)
+	}
+
+	if bytes.Equal(data, tombstone) {
+		return softExpiry, errTombstone
+	}
+	codecID := (header >> headerCodecShift) & headerCodecMask
+
+	var version uint64
+	if header&headerVersionBit != 0 {
+		var n int
+		if version, n = binary.Uvarint(payload); n <= 0 {
+			return softExpiry, fmt.Errorf("%w: invalid type version", ErrCorruptValue)
+		}
+		payload = payload[n:]
+	}
+	if header&headerSoftBit != 0 {
+		ms, n := binary.Uvarint(payload)
+		if n <= 0 {
+			return softExpiry, fmt.Errorf("%w: invalid soft expiry", ErrCorruptValue)
+		}
+		softExpiry = time.UnixMilli(int64(ms))
+		payload = payload[n:]
+	}
+	if err := r.checkVersion(version, v); err != nil {
+		return softExpiry, err
+	}
+
+	codec, err := r.codecByID(codecID)
+	if err != nil {
+		return softExpiry, err
+	}
+
+	payload, err = decompress((header>>headerCompShift)&headerCompMask, payload)
+	if err != nil {
+		return softExpiry, err
+	}
+
+	return softExpiry, codec.Unmarshal(payload, v)
+}
+
+// checkVersion compares the version a value was written with to the current version of the type v points to.
+func (r *RedisCache) checkVersion(version uint64, v any) error {
+	t := reflect.TypeOf(v)
+	if t == nil || t.Kind() != reflect.Pointer {
+		return nil
+	}
+	if current := r.typeVersion(t.Elem()); version != current {
+		return &staleVersionError{older: version < current}
+	}
+	return nil
+}
+
+func (r *RedisCache) codecByID(id uint8) (Codec, error) {
+	if r.codec.ID() == id {
+		return r.codec, nil
+	}
+	for _, c := range builtinCodecs {
+		if c.ID() == id {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: unknown codec id %d", ErrCorruptValue, id)
+}
@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// hdelIfUnchangedScript: KEYS = hash; ARGV = field, value pairs. Deletes the fields still holding their value.
+var hdelIfUnchangedScript = redis.NewScript(`
+local deleted = 0
+for i = 1, #ARGV, 2 do
+	if redis.call("HGET", KEYS[1], ARGV[i]) == ARGV[i + 1] then
+		deleted = deleted + redis.call("HDEL", KEYS[1], ARGV[i])
+	end
+end
+return deleted
+`)
+
+// Hash is a typed handle on a Redis hash whose field values are encoded with the cache codec.
+type Hash[T any] struct {
+	structure
+}
+
+// NewHash binds a typed hash handle to key.
+func NewHash[T any](r *RedisCache, key string) *Hash[T] {
+	return &Hash[T]{structure{r: r, key: r.Key(key)}}
+}
+
+// Get returns the value of field, or redis.Nil when it does not exist.
+func (h *Hash[T]) Get(ctx context.Context, field string) (T, error) {
+	ctx, cancel := h.r.readContext(ctx)
+	defer cancel()
+	data, err := h.r.Universal.HGet(ctx, h.key, field).Result()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	values, err := h.decodeMap(ctx, map[string]string{field: data})
+	value, ok := values[field]
+	if err == nil && !ok {
+		err = redis.Nil
+	}
+	return value, err
+}
+
+// MGet returns the values of the fields that exist.
+func (h *Hash[T]) MGet(ctx context.Context, fields ...string) (map[string]T, error) {
+	ctx, cancel := h.r.readContext(ctx)
+	defer cancel()
+
+	values, err := h.r.Universal.HMGet(ctx, h.key, fields...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]string, len(fields))
+	for i, v := range values {
+		if data, ok := v.(string); ok {
+			raw[fields[i]] = data
+		}
+	}
+	return h.decodeMap(ctx, raw)
+}
+
+// GetAll returns every field of the hash.
+func (h *Hash[T]) GetAll(ctx context.Context) (map[string]T, error) {
+	ctx, cancel := h.r.readContext(ctx)
+	defer cancel()
+
+	raw, err := h.r.Universal.HGetAll(ctx, h.key).Result()
+	if err != nil {
+		return nil, err
+	}
+	return h.decodeMap(ctx, raw)
+}
+
+// Set stores value in field.
+func (h *Hash[T]) Set(ctx context.Context, field string, value T) error {
+	return h.SetMany(ctx, map[string]T{field: value})
+}
+
+// SetMany stores several fields at once.
+func (h *Hash[T]) SetMany(ctx context.Context, values map[string]T) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, len(values)*2)
+	for field, value := range values {
+		data, err := h.r.encode(value)
+		if err != nil {
+			return err
+		}
+		args = append(args, field, data)
+	}
+
+	ctx, cancel := h.r.writeContext(ctx)
+	defer cancel()
+	return h.r.Universal.HSet(ctx, h.key, args...).Err()
+}
+
+// SetNX stores value in field only when the field does not exist yet.
+func (h *Hash[T]) SetNX(ctx context.Context, field string, value T) (bool, error) {
+	data, err := h.r.encode(value)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := h.r.writeContext(ctx)
+	defer cancel()
+	return h.r.Universal.HSetNX(ctx, h.key, field, data).Result()
+}
+
+// IncrBy atomically adds delta to an integer field and returns the new value.
+// Counter fields are stored as plain integers, which Get still decodes for numeric T.
+func (h *Hash[T]) IncrBy(ctx context.Context, field string, delta int64) (int64, error) {
+	ctx, cancel := h.r.writeContext(ctx)
+	defer cancel()
+	return h.r.Universal.HIncrBy(ctx, h.key, field, delta).Result()
+}
+
+// Delete removes fields and returns how many existed.
+func (h *Hash[T]) Delete(ctx context.Context, fields ...string) (int64, error) {
+	ctx, cancel := h.r.writeContext(ctx)
+	defer cancel()
+	return h.r.Universal.HDel(ctx, h.key, fields...).Result()
+}
+
+// Exists reports whether field exists.
+func (h *Hash[T]) Exists(ctx context.Context, field string) (bool, error) {
+	ctx, cancel := h.r.readContext(ctx)
+	defer cancel()
+	return h.r.Universal.HExists(ctx, h.key, field).Result()
+}
+
+// Len returns the number of fields.
+func (h *Hash[T]) Len(ctx context.Context) (int64, error) {
+	ctx, cancel := h.r.readContext(ctx)
+	defer cancel()
+	return h.r.Universal.HLen(ctx, h.key).Result()
+}
+
+// Fields returns the names of every field.
+func (h *Hash[T]) Fields(ctx context.Context) ([]string, error) {
+	ctx, cancel := h.r.readContext(ctx)
+	defer cancel()
+	return h.r.Universal.HKeys(ctx, h.key).Result()
+}
+
+// Scan returns a page of fields matching pattern starting at cursor, and the cursor of the next page.
+// Iteration is complete when the returned cursor is 0.
+func (h *Hash[T]) Scan(ctx context.Context, cursor uint64, pattern string, count int64) (map[string]T, uint64, error) {
+	ctx, cancel := h.r.readContext(ctx)
+	defer cancel()
+
+	pairs, next, err := h.r.Universal.HScan(ctx, h.key, cursor, pattern, count).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	raw := make(map[string]string, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		raw[pairs[i]] = pairs[i+1]
+	}
+	values, err := h.decodeMap(ctx, raw)
+	return values, next, err
+}
+
+// decodeMap decodes field values, skipping and evicting those written with another version of T.
+func (h *Hash[T]) decodeMap(ctx context.Context, raw map[string]string) (map[string]T, error) {
+	result := make(map[string]T, len(raw))
+	var stale []any
+	for field, data := range raw {
+		var value T
+		var err error
+		if isCounter(data) {
+			// Counters written by IncrBy carry no type version, they are neither stale nor evicted.
+			err = json.Unmarshal([]byte(data), &value)
+		} else {
+			err = h.r.decode([]byte(data), &value)
+		}
+		if older, ok := isStale(err); ok {
+			if older {
+				stale = append(stale, field, data)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result[field] = value
+	}
+
+	if len(stale) > 0 {
+		// Eviction is best effort, stale fields are skipped anyway.
+		evictCtx, cancel := h.r.writeContext(context.WithoutCancel(ctx))
+		defer cancel()
+		hdelIfUnchangedScript.Run(evictCtx, h.r.Universal, []string{h.key}, stale...)
+	}
+	return result, nil
+}
+
+// isCounter reports whether data is a plain integer, as stored by HINCRBY.
+func isCounter(data string) bool {
+	_, err := strconv.ParseInt(data, 10, 64)
+	return err == nil
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// List is a typed handle on a Redis list whose elements are encoded with the cache codec.
+type List[T any] struct {
+	structure
+}
+
+// NewList binds a typed list handle to key.
+func NewList[T any](r *RedisCache, key string) *List[T] {
+	l := &List[T]{structure{r: r, key: r.Key(key)}}
+	l.evict = func(ctx context.Context, stale []any) error {
+		ctx, cancel := r.writeContext(ctx)
+		defer cancel()
+		_, err := r.Universal.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for _, value := range stale {
+				pipe.LRem(ctx, l.key, 0, value)
+			}
+			return nil
+		})
+		return err
+	}
+	return l
+}
+
+// PushFront prepends values and returns the new length of the list.
+func (l *List[T]) PushFront(ctx context.Context, values ...T) (int64, error) {
+	encoded, err := encodeAll(l.r, values)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := l.r.writeContext(ctx)
+	defer cancel()
+	return l.r.Universal.LPush(ctx, l.key, encoded...).Result()
+}
+
+// PushBack appends values and returns the new length of the list.
+func (l *List[T]) PushBack(ctx context.Context, values ...T) (int64, error) {
+	encoded, err := encodeAll(l.r, values)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := l.r.writeContext(ctx)
+	defer cancel()
+	return l.r.Universal.RPush(ctx, l.key, encoded...).Result()
+}
+
+// PopFront removes and returns the first element, or redis.Nil when the list is empty.
+func (l *List[T]) PopFront(ctx context.Context) (T, error) {
+	return l.pop(ctx, func(ctx context.Context) (string, error) {
+		return l.r.Universal.LPop(ctx, l.key).Result()
+	}, l.r.Universal.LPush)
+}
+
+// PopBack removes and returns the last element, or redis.Nil when the list is empty.
+func (l *List[T]) PopBack(ctx context.Context) (T, error) {
+	return l.pop(ctx, func(ctx context.Context) (string, error) {
+		return l.r.Universal.RPop(ctx, l.key).Result()
+	}, l.r.Universal.RPush)
+}
+
+// pop pops elements with popOne until one was not written with an older version of T.
+// An element written with a newer version is put back with pushBack, at the end it was popped
+// from, and reported as redis.Nil so that it survives for the replicas that can read it.
+func (l *List[T]) pop(ctx context.Context, popOne func(ctx context.Context) (string, error), pushBack func(ctx context.Context, key string, values ...any) *redis.IntCmd) (T, error) {
+	for {
+		popCtx, cancel := l.r.writeContext(ctx)
+		data, err := popOne(popCtx)
+		cancel()
+
+		var value T
+		if err != nil {
+			return value, err
+		}
+		err = l.r.decode([]byte(data), &value)
+		older, stale := isStale(err)
+		if stale && older {
+			// The older element is removed by the pop, try the next one.
+			continue
+		}
+		if stale {
+			pushCtx, cancel := l.r.writeContext(context.WithoutCancel(ctx))
+			err := pushBack(pushCtx, l.key, data).Err()
+			cancel()
+			var zero T
+			if err != nil {
+				return zero, err
+			}
+			return zero, redis.Nil
+		}
+		return value, err
+	}
+}
+
+// BlockingPopFront waits up to timeout for an element and removes the first one.
+// It returns redis.Nil when the timeout elapses; a zero timeout waits until ctx is done.
+func (l *List[T]) BlockingPopFront(ctx context.Context, timeout time.Duration) (T, error) {
+	// The operation timeout does not apply, the call is expected to block.
+	return l.pop(ctx, func(context.Context) (string, error) {
+		values, err := l.r.Universal.BLPop(ctx, timeout, l.key).Result()
+		if err != nil {
+			return "", err
+		}
+		return values[1], nil
+	}, l.r.Universal.LPush)
+}
+
+// Range returns the elements between start and stop, both inclusive. Negative indexes count
+// from the end of the list, so Range(ctx, 0, -1) returns the whole list.
+func (l *List[T]) Range(ctx context.Context, start, stop int64) ([]T, error) {
+	ctx, cancel := l.r.readContext(ctx)
+	defer cancel()
+
+	data, err := l.r.Universal.LRange(ctx, l.key, start, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeAll[T](ctx, l.structure, data)
+}
+
+// Page returns the page-th page, starting at 0, of size elements.
+func (l *List[T]) Page(ctx context.Context, page, size int64) ([]T, error) {
+	if page < 0 || size <= 0 {
+		return nil, nil
+	}
+	start := page * size
+	return l.Range(ctx, start, start+size-1)
+}
+
+// Index returns the element at index, or redis.Nil when it is out of range.
+func (l *List[T]) Index(ctx context.Context, index int64) (T, error) {
+	ctx, cancel := l.r.readContext(ctx)
+	defer cancel()
+	data, err := l.r.Universal.LIndex(ctx, l.key, index).Result()
+	return decodeOne[T](ctx, l.structure, data, err)
+}
+
+// SetIndex replaces the element at index.
+func (l *List[T]) SetIndex(ctx context.Context, index int64, value T) error {
+	data, err := l.r.encode(value)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := l.r.writeContext(ctx)
+	defer cancel()
+	return l.r.Universal.LSet(ctx, l.key, index, data).Err()
+}
+
+// Remove deletes up to count elements equal to value and returns how many were removed.
+// A positive count removes from the front, a negative one from the back and 0 removes all.
+func (l *List[T]) Remove(ctx context.Context, count int64, value T) (int64, error) {
+	data, err := l.r.encode(value)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := l.r.writeContext(ctx)
+	defer cancel()
+	return l.r.Universal.LRem(ctx, l.key, count, data).Result()
+}
+
+// Trim keeps only the elements between start and stop, both inclusive.
+// Combined with PushFront it keeps a capped list of the most recent elements.
+func (l *List[T]) Trim(ctx context.Context, start, stop int64) error {
+	ctx, cancel := l.r.writeContext(ctx)
+	defer cancel()
+	return l.r.Universal.LTrim(ctx, l.key, start, stop).Err()
+}
+
+// Len returns the length of the list.
+func (l *List[T]) Len(ctx context.Context) (int64, error) {
+	ctx, cancel := l.r.readContext(ctx)
+	defer cancel()
+	return l.r.Universal.LLen(ctx, l.key).Result()
+}
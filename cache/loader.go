@@ -0,0 +1,250 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrLoaderFailed is returned by GetOrLoad when a recent loader failure is cached with LoadErrorCache.
+var ErrLoaderFailed = errors.New("cache: loader failed recently")
+
+// ErrNotFound is returned by loaders when the value does not exist, and by GetOrLoad in that case
+// whatever the load error policy. With WithNegativeTTL the result is cached too.
+var ErrNotFound = errors.New("cache: not found")
+
+type LoadErrorPolicy int
+
+const (
+	// LoadErrorReturn returns the loader error to every waiting caller and caches nothing.
+	LoadErrorReturn LoadErrorPolicy = iota
+	// LoadErrorCache remembers the failure in Redis for the error TTL so that callers on every
+	// replica fail fast with ErrLoaderFailed instead of calling the loader again.
+	LoadErrorCache
+	// LoadErrorIgnore returns the zero value without an error and caches nothing.
+	LoadErrorIgnore
+)
+
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	lockTTL      time.Duration
+	lockWait     time.Duration
+	pollInterval time.Duration
+	errorPolicy  LoadErrorPolicy
+	errorTTL     time.Duration
+	softTTL      time.Duration
+	negativeTTL  time.Duration
+	// refresh is set for the background loads of stale values.
+	refresh bool
+}
+
+// WithDistributedLock deduplicates loads across replicas with a Redis lock held for at most ttl.
+// Replicas that do not get the lock wait for the value to appear for up to wait, then load it themselves.
+func WithDistributedLock(ttl, wait time.Duration) LoadOption {
+	return func(o *loadOptions) {
+		o.lockTTL = ttl
+		o.lockWait = wait
+	}
+}
+
+// WithLoadErrorPolicy sets what happens when the loader fails.
+// ttl is only used by LoadErrorCache.
+func WithLoadErrorPolicy(policy LoadErrorPolicy, ttl time.Duration) LoadOption {
+	return func(o *loadOptions) {
+		o.errorPolicy = policy
+		o.errorTTL = ttl
+	}
+}
+
+// WithSoftTTL marks loaded values stale after soft, before they expire after the hard TTL passed to GetOrLoad.
+// Stale values are still returned, while a single background load per process refreshes them.
+func WithSoftTTL(soft time.Duration) LoadOption {
+	return func(o *loadOptions) {
+		o.softTTL = soft
+	}
+}
+
+// WithNegativeTTL caches for ttl that the loader returned ErrNotFound, so that lookups of missing
+// values do not reach the loader every time. Keep it short, the value may be created meanwhile.
+func WithNegativeTTL(ttl time.Duration) LoadOption {
+	return func(o *loadOptions) {
+		o.negativeTTL = ttl
+	}
+}
+
+// GetOrLoad returns the value cached under key, calling loader and caching its result for ttl on a miss.
+// Concurrent misses for the same key in this process share a single loader call.
+// The loader context is not cancelled when the caller gives up, so that other waiting callers
+// still get the value; loaders should apply their own timeout.
+func GetOrLoad[T any](ctx context.Context, r *RedisCache, key string, ttl time.Duration, loader func(ctx context.Context) (T, error), opts ...LoadOption) (T, error) {
+	var zero T
+
+	o := loadOptions{
+		pollInterval: 50 * time.Millisecond,
+		errorTTL:     5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	flightKey := key + "\x00" + reflect.TypeFor[T]().String()
+
+	value, softExpiry, err := getEntry[T](ctx, r, key)
+	switch {
+	case err == nil:
+		if !softExpiry.IsZero() && time.Now().After(softExpiry) {
+			refresh := o
+			refresh.refresh = true
+			// The result channel is buffered, nobody needs to wait for the refresh.
+			r.loads.DoChan(flightKey, func() (any, error) {
+				return load(context.WithoutCancel(ctx), r, key, ttl, loader, refresh)
+			})
+		}
+		return value, nil
+	case err == errTombstone:
+		return zero, ErrNotFound
+	case err != redis.Nil:
+		return zero, err
+	}
+
+	ch := r.loads.DoChan(flightKey, func() (any, error) {
+		return load(context.WithoutCancel(ctx), r, key, ttl, loader, o)
+	})
+
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			if errors.Is(res.Err, ErrNotFound) {
+				return zero, ErrNotFound
+			}
+			if o.errorPolicy == LoadErrorIgnore {
+				return zero, nil
+			}
+			return zero, res.Err
+		}
+		value, _ := res.Val.(T)
+		return value, nil
+	}
+}
+
+func load[T any](ctx context.Context, r *RedisCache, key string, ttl time.Duration, loader func(ctx context.Context) (T, error), o loadOptions) (T, error) {
+	var zero T
+
+	if o.errorPolicy == LoadErrorCache {
+		msg, err := GetContext[string](ctx, r, loadErrorKey(key))
+		if err == nil {
+			return zero, fmt.Errorf("%w: %s", ErrLoaderFailed, msg)
+		}
+		if err != redis.Nil {
+			return zero, err
+		}
+	}
+
+	if o.lockTTL <= 0 {
+		return loadAndStore(ctx, r, key, ttl, loader, o)
+	}
+
+	token := uuid.NewString()
+	lockKey := loadLockKey(key)
+	acquired, err := r.setLease(ctx, lockKey, token, o.lockTTL)
+	if errors.Is(err, ErrCircuitOpen) {
+		// Nothing can be shared through Redis while the circuit is open.
+		return loadAndStore(ctx, r, key, ttl, loader, o)
+	}
+	if err != nil {
+		return zero, err
+	}
+
+	if acquired {
+		defer func() {
+			releaseCtx, cancel := r.writeContext(context.WithoutCancel(ctx))
+			defer cancel()
+			encoded, err := r.encode(token)
+			if err == nil {
+				releaseLockScript.Run(releaseCtx, r.Universal, []string{r.NamespaceKey(lockKey)}, encoded)
+			}
+		}()
+
+		// Another replica may have stored or refreshed the value between our miss and taking the lock.
+		value, softExpiry, err := getEntry[T](ctx, r, key)
+		if err == nil && (softExpiry.IsZero() || time.Now().Before(softExpiry)) {
+			return value, nil
+		}
+		return loadAndStore(ctx, r, key, ttl, loader, o)
+	}
+
+	// When refreshing, the stale value is found at once and the lock holder refreshes it.
+	if value, err := waitForValue[T](ctx, r, key, o); err != redis.Nil {
+		return value, err
+	}
+	return loadAndStore(ctx, r, key, ttl, loader, o)
+}
+
+// waitForValue polls for the value stored by the replica holding the load lock.
+// It returns redis.Nil when the value did not appear in time and should be loaded by the caller.
+func waitForValue[T any](ctx context.Context, r *RedisCache, key string, o loadOptions) (T, error) {
+	var zero T
+
+	timer := time.NewTimer(o.lockWait)
+	defer timer.Stop()
+	ticker := time.NewTicker(o.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			return zero, redis.Nil
+		case <-ticker.C:
+			value, _, err := getEntry[T](ctx, r, key)
+			switch {
+			case err == nil:
+				return value, nil
+			case err == errTombstone:
+				return zero, ErrNotFound
+			case err != redis.Nil:
+				return zero, redis.Nil
+			}
+		}
+	}
+}
+
+func loadAndStore[T any](ctx context.Context, r *RedisCache, key string, ttl time.Duration, loader func(ctx context.Context) (T, error), o loadOptions) (T, error) {
+	var zero T
+
+	value, err := loader(ctx)
+	if errors.Is(err, ErrNotFound) {
+		if o.negativeTTL > 0 {
+			r.setTombstone(ctx, key, o.negativeTTL)
+		} else if o.refresh {
+			// The stale value no longer exists.
+			DeleteContext(ctx, r, key)
+		}
+		return zero, ErrNotFound
+	}
+	if err != nil {
+		if o.errorPolicy == LoadErrorCache && o.errorTTL > 0 {
+			SetContext(ctx, r, loadErrorKey(key), err.Error(), o.errorTTL)
+		}
+		return zero, err
+	}
+
+	// A failure to cache the value should not fail the caller, the next miss loads it again.
+	r.setEntry(ctx, key, value, ttl, o.softTTL)
+	return value, nil
+}
+
+func loadLockKey(key string) string {
+	return key + ":load-lock"
+}
+
+func loadErrorKey(key string) string {
+	return key + ":load-error"
+}
@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"container/heap"
+	"container/list"
+	"sync"
+	"time"
+)
+
+type EvictionPolicy string
+
+const (
+	EvictionLRU EvictionPolicy = "lru"
+	EvictionLFU EvictionPolicy = "lfu"
+)
+
+type localEntry struct {
+	key       string
+	value     any
+	expiresAt time.Time
+
+	// LRU bookkeeping
+	elem *list.Element
+	// LFU bookkeeping
+	freq     int
+	lastUsed uint64
+	index    int
+}
+
+// localCache is a size-bounded in-memory cache with per-entry expiry and LRU or LFU eviction.
+type localCache struct {
+	mu      sync.Mutex
+	max     int
+	policy  EvictionPolicy
+	items   map[string]*localEntry
+	recency *list.List
+	freqs   lfuHeap
+	clock   uint64
+}
+
+func newLocalCache(max int, policy EvictionPolicy) *localCache {
+	// set evicts until there is room for one entry, which never happens without a positive bound.
+	if max <= 0 {
+		max = 1
+	}
+	return &localCache{
+		max:     max,
+		policy:  policy,
+		items:   make(map[string]*localEntry),
+		recency: list.New(),
+	}
+}
+
+func (c *localCache) get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(e.expiresAt) {
+		c.removeLocked(e)
+		return nil, false
+	}
+	c.touchLocked(e)
+	return e.value, true
+}
+
+func (c *localCache) set(key string, value any, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		e.value = value
+		e.expiresAt = time.Now().Add(ttl)
+		c.touchLocked(e)
+		return
+	}
+
+	for len(c.items) >= c.max {
+		c.evictLocked()
+	}
+
+	e := &localEntry{key: key, value: value, expiresAt: time.Now().Add(ttl)}
+	c.items[key] = e
+	if c.policy == EvictionLFU {
+		c.clock++
+		e.freq = 1
+		e.lastUsed = c.clock
+		heap.Push(&c.freqs, e)
+	} else {
+		e.elem = c.recency.PushFront(e)
+	}
+}
+
+func (c *localCache) delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.removeLocked(e)
+	}
+}
+
+func (c *localCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[string]*localEntry)
+	c.recency.Init()
+	c.freqs = nil
+}
+
+func (c *localCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+func (c *localCache) touchLocked(e *localEntry) {
+	if c.policy == EvictionLFU {
+		c.clock++
+		e.freq++
+		e.lastUsed = c.clock
+		heap.Fix(&c.freqs, e.index)
+		return
+	}
+	c.recency.MoveToFront(e.elem)
+}
+
+func (c *localCache) evictLocked() {
+	if c.policy == EvictionLFU {
+		if len(c.freqs) > 0 {
+			c.removeLocked(c.freqs[0])
+		}
+		return
+	}
+	if back := c.recency.Back(); back != nil {
+		c.removeLocked(back.Value.(*localEntry))
+	}
+}
+
+func (c *localCache) removeLocked(e *localEntry) {
+	delete(c.items, e.key)
+	if c.policy == EvictionLFU {
+		heap.Remove(&c.freqs, e.index)
+		return
+	}
+	c.recency.Remove(e.elem)
+}
+
+// lfuHeap orders entries by access count, breaking ties by least recent use.
+type lfuHeap []*localEntry
+
+func (h lfuHeap) Len() int { return len(h) }
+
+func (h lfuHeap) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].lastUsed < h[j].lastUsed
+}
+
+func (h lfuHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap) Push(x any) {
+	e := x.(*localEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *lfuHeap) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return e
+}
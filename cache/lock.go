@@ -0,0 +1,329 @@
+package cache
+
+import (
+	"context"
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrLockNotObtained is returned by TryLock when the lock is held by another owner.
+	ErrLockNotObtained = errors.New("cache: lock not obtained")
+	// ErrLockNotHeld is returned when extending or releasing a lock that expired or was taken over.
+	ErrLockNotHeld = errors.New("cache: lock not held")
+)
+
+// The lock and its fencing counter share a hash tag so the scripts also work in cluster mode.
+var (
+	acquireLockScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return redis.call("INCR", KEYS[2])
+end
+return 0
+`)
+
+	releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+	extendLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+)
+
+type LockOption func(*lockOptions)
+
+type lockOptions struct {
+	ttl           time.Duration
+	autoRenew     bool
+	renewInterval time.Duration
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+}
+
+// WithLockTTL sets how long the lock is held without being renewed. Defaults to 30 seconds.
+func WithLockTTL(ttl time.Duration) LockOption {
+	return func(o *lockOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithAutoRenew starts a watchdog that extends the lock every interval while it is held.
+// A zero interval renews at a third of the TTL.
+func WithAutoRenew(interval time.Duration) LockOption {
+	return func(o *lockOptions) {
+		o.autoRenew = true
+		o.renewInterval = interval
+	}
+}
+
+// WithLockBackoff sets the exponential backoff bounds used by Lock between attempts.
+// Bounds that are not positive keep their defaults of 10ms and 1s, and maxBackoff is raised to minBackoff.
+func WithLockBackoff(minBackoff, maxBackoff time.Duration) LockOption {
+	return func(o *lockOptions) {
+		o.minBackoff = minBackoff
+		o.maxBackoff = maxBackoff
+	}
+}
+
+// Locker creates distributed locks on one Redis node, or on several independent nodes
+// following the Redlock algorithm.
+type Locker struct {
+	nodes []*RedisCache
+	opts  lockOptions
+}
+
+// NewLocker creates a locker backed by a single Redis deployment.
+func NewLocker(r *RedisCache, opts ...LockOption) *Locker {
+	return newLocker([]*RedisCache{r}, opts)
+}
+
+// NewRedlock creates a locker that requires a majority of independent Redis nodes to agree.
+func NewRedlock(nodes []*RedisCache, opts ...LockOption) (*Locker, error) {
+	if len(nodes) == 0 {
+		return nil, errors.New("cache: redlock requires at least one node")
+	}
+	return newLocker(nodes, opts), nil
+}
+
+func newLocker(nodes []*RedisCache, opts []LockOption) *Locker {
+	o := lockOptions{
+		ttl:        30 * time.Second,
+		minBackoff: 10 * time.Millisecond,
+		maxBackoff: time.Second,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.minBackoff <= 0 {
+		o.minBackoff = 10 * time.Millisecond
+	}
+	if o.maxBackoff <= 0 {
+		o.maxBackoff = time.Second
+	}
+	o.maxBackoff = max(o.maxBackoff, o.minBackoff)
+	if o.autoRenew && o.renewInterval <= 0 {
+		o.renewInterval = o.ttl / 3
+	}
+	return &Locker{nodes: nodes, opts: o}
+}
+
+// Lock is a held distributed lock.
+type Lock struct {
+	locker *Locker
+	key    string
+	token  string
+	fence  int64
+
+	mu       sync.Mutex
+	released bool
+	stop     chan struct{}
+	lost     chan struct{}
+	lostOnce sync.Once
+}
+
+// TryLock acquires the lock called name once, returning ErrLockNotObtained when it is held elsewhere.
+func (l *Locker) TryLock(ctx context.Context, name string) (*Lock, error) {
+	token, err := newLockToken()
+	if err != nil {
+		return nil, err
+	}
+
+	key := lockKey(name)
+	start := time.Now()
+
+	var granted []*RedisCache
+	var fence int64
+	var lastErr error
+	for _, node := range l.nodes {
+		n, err := acquireOnNode(ctx, node, key, token, l.opts.ttl)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if n > 0 {
+			granted = append(granted, node)
+			fence = max(fence, n)
+		}
+	}
+
+	// The lock is only valid when a majority granted it with time to spare, accounting for clock drift.
+	drift := l.opts.ttl/100 + 2*time.Millisecond
+	validity := l.opts.ttl - time.Since(start) - drift
+	if len(granted) < l.quorum() || validity <= 0 {
+		releaseOnNodes(context.WithoutCancel(ctx), granted, key, token)
+		if lastErr != nil && len(granted) < l.quorum() {
+			return nil, lastErr
+		}
+		return nil, ErrLockNotObtained
+	}
+
+	lock := &Lock{
+		locker: l,
+		key:    key,
+		token:  token,
+		fence:  fence,
+		stop:   make(chan struct{}),
+		lost:   make(chan struct{}),
+	}
+	if l.opts.autoRenew {
+		go lock.watchdog()
+	}
+	return lock, nil
+}
+
+// Lock blocks until the lock called name is acquired or ctx is done, retrying with exponential backoff.
+func (l *Locker) Lock(ctx context.Context, name string) (*Lock, error) {
+	backoff := l.opts.minBackoff
+	for {
+		lock, err := l.TryLock(ctx, name)
+		if err == nil {
+			return lock, nil
+		}
+		if !errors.Is(err, ErrLockNotObtained) {
+			return nil, err
+		}
+
+		// Full jitter keeps competing replicas from retrying in lockstep.
+		wait := time.Duration(rand.Int64N(int64(backoff)) + 1)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, l.opts.maxBackoff)
+	}
+}
+
+// Token returns the random owner token stored in the lock.
+func (lock *Lock) Token() string {
+	return lock.token
+}
+
+// FencingToken returns a number that increases every time the lock is acquired.
+// Pass it to the protected resource so it can reject writes from an owner whose lock expired.
+// With NewRedlock it is the highest of the counters of the granting nodes, which is not guaranteed
+// to increase: a later owner granted by other nodes can get a lower token. Only rely on it for
+// fencing with a single Redis deployment.
+func (lock *Lock) FencingToken() int64 {
+	return lock.fence
+}
+
+// Lost is closed when the watchdog fails to renew the lock.
+func (lock *Lock) Lost() <-chan struct{} {
+	return lock.lost
+}
+
+// Extend resets the TTL of the lock if it is still held by this owner.
+func (lock *Lock) Extend(ctx context.Context, ttl time.Duration) error {
+	l := lock.locker
+	extended := 0
+	for _, node := range l.nodes {
+		nodeCtx, cancel := node.writeContext(ctx)
+		n, err := extendLockScript.Run(nodeCtx, node.Universal, []string{node.NamespaceKey(lock.key)}, lock.token, ttl.Milliseconds()).Int64()
+		cancel()
+		if err == nil && n == 1 {
+			extended++
+		}
+	}
+	if extended < l.quorum() {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Unlock releases the lock if it is still held by this owner and stops the watchdog.
+func (lock *Lock) Unlock(ctx context.Context) error {
+	lock.mu.Lock()
+	if lock.released {
+		lock.mu.Unlock()
+		return ErrLockNotHeld
+	}
+	lock.released = true
+	close(lock.stop)
+	lock.mu.Unlock()
+
+	if releaseOnNodes(ctx, lock.locker.nodes, lock.key, lock.token) < lock.locker.quorum() {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+func (lock *Lock) watchdog() {
+	ticker := time.NewTicker(lock.locker.opts.renewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lock.stop:
+			return
+		case <-ticker.C:
+			if err := lock.Extend(context.Background(), lock.locker.opts.ttl); err != nil {
+				lock.lostOnce.Do(func() { close(lock.lost) })
+				return
+			}
+		}
+	}
+}
+
+func (l *Locker) quorum() int {
+	return len(l.nodes)/2 + 1
+}
+
+func acquireOnNode(ctx context.Context, node *RedisCache, key, token string, ttl time.Duration) (int64, error) {
+	key = node.NamespaceKey(key)
+	ctx, cancel := node.writeContext(ctx)
+	defer cancel()
+	return acquireLockScript.Run(ctx, node.Universal, []string{key, key + ":fence"}, token, ttl.Milliseconds()).Int64()
+}
+
+// releaseOnNodes deletes the lock on every node where it is owned by token and returns how many released it.
+func releaseOnNodes(ctx context.Context, nodes []*RedisCache, key, token string) int {
+	released := 0
+	for _, node := range nodes {
+		nodeCtx, cancel := node.writeContext(ctx)
+		n, err := releaseLockScript.Run(nodeCtx, node.Universal, []string{node.NamespaceKey(key)}, token).Int64()
+		cancel()
+		if err == nil && n == 1 {
+			released++
+		}
+	}
+	return released
+}
+
+// setLease stores the encoded value under the coordination key of key only when it does not exist yet.
+// Like the other lock keys it is prefixed with NamespaceKey, so it is shared between schema versions.
+func (r *RedisCache) setLease(ctx context.Context, key string, value any, ttl time.Duration) (bool, error) {
+	data, err := r.encode(value)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := r.writeContext(ctx)
+	defer cancel()
+	return r.Universal.SetNX(ctx, r.NamespaceKey(key), data, ttl).Result()
+}
+
+func lockKey(name string) string {
+	return "lock:{" + name + "}"
+}
+
+func newLockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := cryptorand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,434 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Clock tells the current time to a MemoryStore.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// ManualClock is a Clock that only moves when told to, for deterministic expiry in tests.
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewManualClock creates a clock stopped at now.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to t.
+func (c *ManualClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+type MemoryStoreOption func(*memoryStoreOptions)
+
+type memoryStoreOptions struct {
+	clock Clock
+	codec Codec
+}
+
+// WithClock replaces the system clock used to expire keys.
+func WithClock(clock Clock) MemoryStoreOption {
+	return func(o *memoryStoreOptions) {
+		o.clock = clock
+	}
+}
+
+// WithMemoryCodec replaces the JSON codec used to copy values in and out of the store.
+func WithMemoryCodec(codec Codec) MemoryStoreOption {
+	return func(o *memoryStoreOptions) {
+		o.codec = codec
+	}
+}
+
+// MemoryStore is an in-process Store with the same TTL semantics as Redis, meant for tests.
+// Values are encoded on write so that callers never share memory with the store.
+type MemoryStore struct {
+	clock Clock
+	codec Codec
+
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	subs    map[*memorySubscription]struct{}
+}
+
+type memoryEntry struct {
+	data []byte
+	// expiresAt is zero for keys without expiry.
+	expiresAt time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore(opts ...MemoryStoreOption) *MemoryStore {
+	o := memoryStoreOptions{clock: systemClock{}, codec: JSONCodec}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &MemoryStore{
+		clock:   o.clock,
+		codec:   o.codec,
+		entries: make(map[string]memoryEntry),
+		subs:    make(map[*memorySubscription]struct{}),
+	}
+}
+
+// lookup returns the live entry of key, dropping it when expired. The caller holds mu.
+func (s *MemoryStore) lookup(key string) (memoryEntry, bool) {
+	e, ok := s.entries[key]
+	if !ok {
+		return e, false
+	}
+	if !e.expiresAt.IsZero() && !s.clock.Now().Before(e.expiresAt) {
+		delete(s.entries, key)
+		return e, false
+	}
+	return e, true
+}
+
+// expiresAt returns the expiry time of a key written now with expiration, zero for none.
+func (s *MemoryStore) expiresAt(expiration time.Duration) time.Time {
+	if expiration <= 0 {
+		return time.Time{}
+	}
+	return s.clock.Now().Add(expiration)
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string, dest any) error {
+	s.mu.Lock()
+	e, ok := s.lookup(key)
+	s.mu.Unlock()
+
+	if !ok {
+		return redis.Nil
+	}
+	return s.codec.Unmarshal(e.data, dest)
+}
+
+func (s *MemoryStore) Set(ctx context.Context, key string, value any, expiration time.Duration) error {
+	data, err := s.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = memoryEntry{data: data, expiresAt: s.expiresAt(expiration)}
+	return nil
+}
+
+func (s *MemoryStore) SetNX(ctx context.Context, key string, value any, expiration time.Duration) (bool, error) {
+	data, err := s.codec.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.lookup(key); ok {
+		return false, nil
+	}
+	s.entries[key] = memoryEntry{data: data, expiresAt: s.expiresAt(expiration)}
+	return true, nil
+}
+
+func (s *MemoryStore) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.lookup(key)
+	if !ok {
+		return false, nil
+	}
+	if expiration <= 0 {
+		delete(s.entries, key)
+		return true, nil
+	}
+	e.expiresAt = s.expiresAt(expiration)
+	s.entries[key] = e
+	return true, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, keys ...string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	for _, key := range keys {
+		if _, ok := s.lookup(key); ok {
+			delete(s.entries, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s *MemoryStore) CompareAndDelete(ctx context.Context, key string, value any) (bool, error) {
+	data, err := s.codec.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.lookup(key)
+	if !ok || !bytes.Equal(e.data, data) {
+		return false, nil
+	}
+	delete(s.entries, key)
+	return true, nil
+}
+
+func (s *MemoryStore) CompareAndExpire(ctx context.Context, key string, value any, expiration time.Duration) (bool, error) {
+	data, err := s.codec.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.lookup(key)
+	if !ok || !bytes.Equal(e.data, data) {
+		return false, nil
+	}
+	if expiration <= 0 {
+		delete(s.entries, key)
+		return true, nil
+	}
+	e.expiresAt = s.expiresAt(expiration)
+	s.entries[key] = e
+	return true, nil
+}
+
+func (s *MemoryStore) Exists(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.lookup(key)
+	return ok, nil
+}
+
+// Leases returns s itself.
+func (s *MemoryStore) Leases() LeaseStore {
+	return s
+}
+
+// TTL returns the remaining time to live of key, -1 when it has no expiry and -2 when it does not exist,
+// like the Redis PTTL command.
+func (s *MemoryStore) TTL(key string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.lookup(key)
+	switch {
+	case !ok:
+		return -2
+	case e.expiresAt.IsZero():
+		return -1
+	}
+	return e.expiresAt.Sub(s.clock.Now())
+}
+
+// Scan calls fn for the matching keys in sorted order. Keys may be written by fn.
+func (s *MemoryStore) Scan(ctx context.Context, pattern string, fn func(key string) error) error {
+	s.mu.Lock()
+	var keys []string
+	for key := range s.entries {
+		if _, ok := s.lookup(key); ok && globMatch(pattern, key) {
+			keys = append(keys, key)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Publish delivers payload to the current subscribers of channel. Like Redis, messages are dropped
+// for subscribers that are too slow to consume them.
+func (s *MemoryStore) Publish(ctx context.Context, channel string, payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for sub := range s.subs {
+		if _, ok := sub.channels[channel]; !ok {
+			continue
+		}
+		msg := &Message{Channel: channel, Payload: append([]byte(nil), payload...)}
+		select {
+		case sub.ch <- msg:
+		default:
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Subscribe(ctx context.Context, channels ...string) (Subscription, error) {
+	sub := &memorySubscription{
+		store:    s,
+		channels: make(map[string]struct{}, len(channels)),
+		ch:       make(chan *Message, 100),
+		done:     make(chan struct{}),
+	}
+	for _, channel := range channels {
+		sub.channels[channel] = struct{}{}
+	}
+
+	s.mu.Lock()
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			sub.Close()
+		case <-sub.done:
+		}
+	}()
+	return sub, nil
+}
+
+type memorySubscription struct {
+	store    *MemoryStore
+	channels map[string]struct{}
+	ch       chan *Message
+	done     chan struct{}
+	once     sync.Once
+}
+
+func (s *memorySubscription) Channel() <-chan *Message {
+	return s.ch
+}
+
+func (s *memorySubscription) Close() error {
+	s.once.Do(func() {
+		s.store.mu.Lock()
+		delete(s.store.subs, s)
+		close(s.ch)
+		s.store.mu.Unlock()
+		close(s.done)
+	})
+	return nil
+}
+
+// globMatch reports whether key matches a Redis glob pattern: * and ? wildcards,
+// [abc], [a-z] and [^a] classes, and \ escapes. It backtracks to the last * only,
+// so it runs in O(len(pattern)*len(key)) however many stars the pattern has.
+func globMatch(pattern, key string) bool {
+	p, k := 0, 0
+	// The position of the last * in pattern and of the key byte it is matched up to.
+	star, starKey := -1, 0
+	for k < len(key) {
+		if p < len(pattern) {
+			if pattern[p] == '*' {
+				star, starKey = p, k
+				p++
+				continue
+			}
+			if n, ok := matchOne(pattern[p:], key[k]); ok {
+				p += n
+				k++
+				continue
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		// Let the last * absorb one more byte and try again from there.
+		starKey++
+		p, k = star+1, starKey
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchOne matches c against the single byte token at the start of pattern and returns the length of the token.
+func matchOne(pattern string, c byte) (int, bool) {
+	switch pattern[0] {
+	case '?':
+		return 1, true
+	case '[':
+		end := 1
+		if end < len(pattern) && pattern[end] == '^' {
+			end++
+		}
+		if end < len(pattern) && pattern[end] == ']' {
+			end++
+		}
+		for end < len(pattern) && pattern[end] != ']' {
+			end++
+		}
+		if end == len(pattern) {
+			// An unterminated class matches literally, as in Redis.
+			return 1, c == '['
+		}
+		return end + 1, classMatch(pattern[1:end], c)
+	case '\\':
+		if len(pattern) > 1 {
+			return 2, pattern[1] == c
+		}
+	}
+	return 1, pattern[0] == c
+}
+
+func classMatch(class string, c byte) bool {
+	negate := len(class) > 0 && class[0] == '^'
+	if negate {
+		class = class[1:]
+	}
+	matched := false
+	for i := 0; i < len(class); i++ {
+		if i+2 < len(class) && class[i+1] == '-' {
+			if class[i] <= c && c <= class[i+2] {
+				matched = true
+			}
+			i += 2
+			continue
+		}
+		if class[i] == c {
+			matched = true
+		}
+	}
+	return matched != negate
+}
@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern, key string
+		want         bool
+	}{
+		{"", "", true},
+		{"", "a", false},
+		{"abc", "abc", true},
+		{"abc", "abd", false},
+
+		{"*", "", true},
+		{"*", "anything", true},
+		{"user:*", "user:42", true},
+		{"user:*", "order:42", false},
+		{"*:42", "user:42", true},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+		{"**", "ab", true},
+		{"a*a*a*a*b", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", false},
+
+		{"?", "a", true},
+		{"?", "", false},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+
+		{"[abc]", "b", true},
+		{"[abc]", "d", false},
+		{"[a-c]x", "bx", true},
+		{"[a-c]x", "dx", false},
+		{"[^a]", "b", true},
+		{"[^a]", "a", false},
+		{"[]]", "]", true},
+		{"[ab", "[ab", true},
+
+		{`\*`, "*", true},
+		{`\*`, "a", false},
+		{`a\?`, "a?", true},
+		{`a\?`, "ab", false},
+		{`\[a]`, "[a]", true},
+		{`\[a]`, "a", false},
+		{`a\\b`, `a\b`, true},
+
+		// A namespace with metacharacters only matches itself once escaped.
+		{escapeGlob("ns[1]*:") + "*", "ns[1]*:key", true},
+		{escapeGlob("ns[1]*:") + "*", "ns1x:key", false},
+		{escapeGlob("n?:") + "k", "n?:k", true},
+		{escapeGlob("n?:") + "k", "nx:k", false},
+	}
+	for _, tt := range tests {
+		if got := globMatch(tt.pattern, tt.key); got != tt.want {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", tt.pattern, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.Unix(0, 0))
+	s := NewMemoryStore(WithClock(clock))
+
+	if err := s.Set(ctx, "k", "v", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set(ctx, "forever", "v", 0); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := s.TTL("k"); ttl != time.Second {
+		t.Fatalf("TTL = %v, want 1s", ttl)
+	}
+	if ttl := s.TTL("forever"); ttl != -1 {
+		t.Fatalf("TTL without expiry = %v, want -1", ttl)
+	}
+
+	clock.Advance(999 * time.Millisecond)
+	if ok, _ := s.Exists(ctx, "k"); !ok {
+		t.Fatal("key expired before its TTL")
+	}
+
+	clock.Advance(time.Millisecond)
+	var got string
+	if err := s.Get(ctx, "k", &got); !errors.Is(err, redis.Nil) {
+		t.Fatalf("Get after expiry = %v, want redis.Nil", err)
+	}
+	if ttl := s.TTL("k"); ttl != -2 {
+		t.Fatalf("TTL after expiry = %v, want -2", ttl)
+	}
+	if ok, _ := s.Exists(ctx, "forever"); !ok {
+		t.Fatal("key without expiry expired")
+	}
+
+	if ok, err := s.Expire(ctx, "forever", time.Minute); err != nil || !ok {
+		t.Fatalf("Expire = %v, %v, want true", ok, err)
+	}
+	if ok, err := s.Expire(ctx, "forever", 0); err != nil || !ok {
+		t.Fatalf("Expire(0) = %v, %v, want true", ok, err)
+	}
+	if ok, _ := s.Exists(ctx, "forever"); ok {
+		t.Fatal("Expire(0) did not delete the key")
+	}
+	if ok, err := s.Expire(ctx, "missing", time.Minute); err != nil || ok {
+		t.Fatalf("Expire of a missing key = %v, %v, want false", ok, err)
+	}
+}
+
+func TestMemoryStoreSetNX(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.Unix(0, 0))
+	s := NewMemoryStore(WithClock(clock))
+
+	if ok, err := s.SetNX(ctx, "k", "a", time.Second); err != nil || !ok {
+		t.Fatalf("SetNX = %v, %v, want true", ok, err)
+	}
+	if ok, err := s.SetNX(ctx, "k", "b", time.Second); err != nil || ok {
+		t.Fatalf("SetNX of an existing key = %v, %v, want false", ok, err)
+	}
+
+	clock.Advance(time.Second)
+	if ok, err := s.SetNX(ctx, "k", "b", time.Second); err != nil || !ok {
+		t.Fatalf("SetNX of an expired key = %v, %v, want true", ok, err)
+	}
+	var got string
+	if err := s.Get(ctx, "k", &got); err != nil || got != "b" {
+		t.Fatalf("Get = %q, %v, want b", got, err)
+	}
+}
+
+func TestMemoryStoreCompareAndDelete(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.Unix(0, 0))
+	s := NewMemoryStore(WithClock(clock))
+
+	if err := s.Set(ctx, "k", "owner", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.CompareAndDelete(ctx, "k", "other"); err != nil || ok {
+		t.Fatalf("CompareAndDelete with another value = %v, %v, want false", ok, err)
+	}
+	if ok, _ := s.Exists(ctx, "k"); !ok {
+		t.Fatal("CompareAndDelete with another value deleted the key")
+	}
+	if ok, err := s.CompareAndDelete(ctx, "k", "owner"); err != nil || !ok {
+		t.Fatalf("CompareAndDelete = %v, %v, want true", ok, err)
+	}
+	if ok, _ := s.Exists(ctx, "k"); ok {
+		t.Fatal("CompareAndDelete did not delete the key")
+	}
+
+	if err := s.Set(ctx, "k", "owner", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Second)
+	if ok, err := s.CompareAndDelete(ctx, "k", "owner"); err != nil || ok {
+		t.Fatalf("CompareAndDelete of an expired key = %v, %v, want false", ok, err)
+	}
+}
+
+func TestMemoryStoreCompareAndExpire(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.Unix(0, 0))
+	s := NewMemoryStore(WithClock(clock))
+
+	if err := s.Set(ctx, "k", "owner", time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.CompareAndExpire(ctx, "k", "other", time.Minute); err != nil || ok {
+		t.Fatalf("CompareAndExpire with another value = %v, %v, want false", ok, err)
+	}
+	if ttl := s.TTL("k"); ttl != time.Second {
+		t.Fatalf("TTL after a failed CompareAndExpire = %v, want 1s", ttl)
+	}
+	if ok, err := s.CompareAndExpire(ctx, "k", "owner", time.Minute); err != nil || !ok {
+		t.Fatalf("CompareAndExpire = %v, %v, want true", ok, err)
+	}
+
+	clock.Advance(30 * time.Second)
+	if ttl := s.TTL("k"); ttl != 30*time.Second {
+		t.Fatalf("TTL = %v, want 30s", ttl)
+	}
+	if ok, err := s.CompareAndExpire(ctx, "k", "owner", 0); err != nil || !ok {
+		t.Fatalf("CompareAndExpire(0) = %v, %v, want true", ok, err)
+	}
+	if ok, _ := s.Exists(ctx, "k"); ok {
+		t.Fatal("CompareAndExpire(0) did not delete the key")
+	}
+	if ok, err := s.CompareAndExpire(ctx, "k", "owner", time.Minute); err != nil || ok {
+		t.Fatalf("CompareAndExpire of a missing key = %v, %v, want false", ok, err)
+	}
+}
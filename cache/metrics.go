@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Command outcomes recorded by the metrics.
+const (
+	resultHit     = "hit"
+	resultMiss    = "miss"
+	resultSuccess = "success"
+	resultError   = "error"
+)
+
+// lookupCommands are the commands whose nil reply is a cache miss.
+var lookupCommands = map[string]bool{
+	"get": true, "getex": true, "getdel": true, "hget": true, "lindex": true, "zscore": true,
+	"mget": true, "hmget": true,
+}
+
+type MetricsOption func(*metricsOptions)
+
+type metricsOptions struct {
+	keyPrefix func(key string) string
+	buckets   []float64
+}
+
+// WithKeyPrefix sets how keys, without the namespace, map to the prefix label.
+// It must return a small set of values. The default keeps the text before the first colon.
+func WithKeyPrefix(fn func(key string) string) MetricsOption {
+	return func(o *metricsOptions) {
+		o.keyPrefix = fn
+	}
+}
+
+// WithLatencyBuckets sets the upper bounds in seconds of the latency histogram buckets.
+func WithLatencyBuckets(buckets []float64) MetricsOption {
+	return func(o *metricsOptions) {
+		o.buckets = buckets
+	}
+}
+
+func newMetricsOptions(opts []MetricsOption) metricsOptions {
+	o := metricsOptions{
+		keyPrefix: defaultKeyPrefix,
+		buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func defaultKeyPrefix(key string) string {
+	if prefix, _, ok := strings.Cut(key, ":"); ok {
+		return prefix
+	}
+	return "other"
+}
+
+// metricsSink receives the measurements of a metricsHook.
+type metricsSink interface {
+	observeLatency(ctx context.Context, command string, d time.Duration)
+	countResult(ctx context.Context, command, prefix, result string)
+}
+
+// metricsHook measures every command sent through the client, including raw Client calls.
+type metricsHook struct {
+	r    *RedisCache
+	opts metricsOptions
+	sink metricsSink
+}
+
+// addMetricsHook measures the commands of r with h. The breaker hook runs first and does not pass
+// the commands it rejects on, so it counts them in h itself.
+func (r *RedisCache) addMetricsHook(h *metricsHook) {
+	r.Universal.AddHook(h)
+	if r.breaker != nil {
+		r.breaker.observe(h)
+	}
+}
+
+func (h *metricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *metricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.sink.observeLatency(ctx, cmd.Name(), time.Since(start))
+		// The error is only set on cmd once the hooks returned.
+		h.countResults(ctx, cmd, err)
+		return err
+	}
+}
+
+func (h *metricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		h.sink.observeLatency(ctx, "pipeline", time.Since(start))
+		for _, cmd := range cmds {
+			h.countResults(ctx, cmd, cmd.Err())
+		}
+		return err
+	}
+}
+
+func (h *metricsHook) countResults(ctx context.Context, cmd redis.Cmder, err error) {
+	name := cmd.Name()
+	args := cmd.Args()
+
+	if err != nil && err != redis.Nil {
+		h.sink.countResult(ctx, name, h.prefix(commandKey(name, args)), resultError)
+		return
+	}
+
+	if !lookupCommands[name] {
+		h.sink.countResult(ctx, name, h.prefix(commandKey(name, args)), resultSuccess)
+		return
+	}
+
+	// Multi-key lookups count one hit or miss per requested key.
+	if values, ok := cmd.(*redis.SliceCmd); ok {
+		for i, v := range values.Val() {
+			key := commandKey(name, args)
+			if name == "mget" && i+1 < len(args) {
+				key, _ = args[i+1].(string)
+			}
+			result := resultHit
+			if v == nil {
+				result = resultMiss
+			}
+			h.sink.countResult(ctx, name, h.prefix(key), result)
+		}
+		return
+	}
+
+	result := resultHit
+	if err == redis.Nil {
+		result = resultMiss
+	}
+	h.sink.countResult(ctx, name, h.prefix(commandKey(name, args)), result)
+}
+
+// prefix returns the prefix label of a Redis key.
+func (h *metricsHook) prefix(key string) string {
+	if key == "" {
+		return ""
+	}
+	key = strings.TrimPrefix(key, h.r.Key(""))
+	key = strings.TrimPrefix(key, h.r.NamespaceKey(""))
+	return h.opts.keyPrefix(key)
+}
+
+// commandKey returns the first key a command operates on, or "" for commands without keys.
+func commandKey(name string, args []any) string {
+	index := 1
+	switch name {
+	case "eval", "evalsha", "eval_ro", "evalsha_ro", "fcall", "fcall_ro":
+		index = 3
+	case "ping", "publish", "subscribe", "psubscribe", "unsubscribe", "punsubscribe",
+		"scan", "info", "hello", "auth", "select", "client", "cluster", "command", "time", "dbsize":
+		return ""
+	}
+	if index < len(args) {
+		key, _ := args[index].(string)
+		return key
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+type otelSink struct {
+	latency  metric.Float64Histogram
+	requests metric.Int64Counter
+}
+
+// RegisterOTelMetrics instruments r and records its command latency, hit/miss/error counts and
+// connection pool stats with instruments created from meter.
+func RegisterOTelMetrics(r *RedisCache, meter metric.Meter, opts ...MetricsOption) error {
+	o := newMetricsOptions(opts)
+
+	latency, err := meter.Float64Histogram("cache.command.duration",
+		metric.WithDescription("Latency of Redis commands and pipelines."),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(o.buckets...))
+	if err != nil {
+		return err
+	}
+	requests, err := meter.Int64Counter("cache.requests",
+		metric.WithDescription("Redis commands by key prefix and result: hit or miss for lookups, success or error otherwise."))
+	if err != nil {
+		return err
+	}
+
+	poolHits, err := meter.Int64ObservableCounter("cache.pool.hits",
+		metric.WithDescription("Times a free connection was found in the pool."))
+	if err != nil {
+		return err
+	}
+	poolMisses, err := meter.Int64ObservableCounter("cache.pool.misses",
+		metric.WithDescription("Times a free connection was not found in the pool."))
+	if err != nil {
+		return err
+	}
+	poolTimeouts, err := meter.Int64ObservableCounter("cache.pool.timeouts",
+		metric.WithDescription("Times a wait for a connection timed out."))
+	if err != nil {
+		return err
+	}
+	poolConns, err := meter.Int64ObservableGauge("cache.pool.connections",
+		metric.WithDescription("Connections in the pool by state."))
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, obs metric.Observer) error {
+		stats := r.Universal.PoolStats()
+		obs.ObserveInt64(poolHits, int64(stats.Hits))
+		obs.ObserveInt64(poolMisses, int64(stats.Misses))
+		obs.ObserveInt64(poolTimeouts, int64(stats.Timeouts))
+		obs.ObserveInt64(poolConns, int64(stats.TotalConns), metric.WithAttributes(attribute.String("state", "total")))
+		obs.ObserveInt64(poolConns, int64(stats.IdleConns), metric.WithAttributes(attribute.String("state", "idle")))
+		obs.ObserveInt64(poolConns, int64(stats.StaleConns), metric.WithAttributes(attribute.String("state", "stale")))
+		return nil
+	}, poolHits, poolMisses, poolTimeouts, poolConns)
+	if err != nil {
+		return err
+	}
+
+	r.addMetricsHook(&metricsHook{r: r, opts: o, sink: &otelSink{latency: latency, requests: requests}})
+	return nil
+}
+
+func (s *otelSink) observeLatency(ctx context.Context, command string, d time.Duration) {
+	s.latency.Record(ctx, d.Seconds(), metric.WithAttributes(attribute.String("command", command)))
+}
+
+func (s *otelSink) countResult(ctx context.Context, command, prefix, result string) {
+	s.requests.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("command", command),
+		attribute.String("prefix", prefix),
+		attribute.String("result", result),
+	))
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PrometheusCollector exposes the command latency, hit/miss/error counts and connection pool
+// stats of a RedisCache.
+type PrometheusCollector struct {
+	r        *RedisCache
+	latency  *prometheus.HistogramVec
+	requests *prometheus.CounterVec
+
+	poolHits     *prometheus.Desc
+	poolMisses   *prometheus.Desc
+	poolTimeouts *prometheus.Desc
+	poolConns    *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*PrometheusCollector)(nil)
+
+// NewPrometheusCollector instruments r and returns the collector of its metrics, to be registered
+// with a prometheus.Registerer.
+func NewPrometheusCollector(r *RedisCache, opts ...MetricsOption) *PrometheusCollector {
+	o := newMetricsOptions(opts)
+	c := &PrometheusCollector{
+		r: r,
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "cache_command_duration_seconds",
+			Help:    "Latency of Redis commands and pipelines.",
+			Buckets: o.buckets,
+		}, []string{"command"}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_requests_total",
+			Help: "Redis commands by key prefix and result: hit or miss for lookups, success or error otherwise.",
+		}, []string{"command", "prefix", "result"}),
+		poolHits:     prometheus.NewDesc("cache_pool_hits_total", "Times a free connection was found in the pool.", nil, nil),
+		poolMisses:   prometheus.NewDesc("cache_pool_misses_total", "Times a free connection was not found in the pool.", nil, nil),
+		poolTimeouts: prometheus.NewDesc("cache_pool_timeouts_total", "Times a wait for a connection timed out.", nil, nil),
+		poolConns:    prometheus.NewDesc("cache_pool_connections", "Connections in the pool by state.", []string{"state"}, nil),
+	}
+	r.addMetricsHook(&metricsHook{r: r, opts: o, sink: c})
+	return c
+}
+
+func (c *PrometheusCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.latency.Describe(ch)
+	c.requests.Describe(ch)
+	ch <- c.poolHits
+	ch <- c.poolMisses
+	ch <- c.poolTimeouts
+	ch <- c.poolConns
+}
+
+func (c *PrometheusCollector) Collect(ch chan<- prometheus.Metric) {
+	c.latency.Collect(ch)
+	c.requests.Collect(ch)
+
+	stats := c.r.Universal.PoolStats()
+	ch <- prometheus.MustNewConstMetric(c.poolHits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.poolMisses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.poolTimeouts, prometheus.CounterValue, float64(stats.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.poolConns, prometheus.GaugeValue, float64(stats.TotalConns), "total")
+	ch <- prometheus.MustNewConstMetric(c.poolConns, prometheus.GaugeValue, float64(stats.IdleConns), "idle")
+	ch <- prometheus.MustNewConstMetric(c.poolConns, prometheus.GaugeValue, float64(stats.StaleConns), "stale")
+}
+
+func (c *PrometheusCollector) observeLatency(_ context.Context, command string, d time.Duration) {
+	c.latency.WithLabelValues(command).Observe(d.Seconds())
+}
+
+func (c *PrometheusCollector) countResult(_ context.Context, command, prefix, result string) {
+	c.requests.WithLabelValues(command, prefix, result).Inc()
+}
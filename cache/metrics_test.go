@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestMetricsCountCommandsRejectedByTheBreaker(t *testing.T) {
+	ctx := context.Background()
+	r, m := newTestRedisCache(t, Config{CircuitBreaker: CircuitBreakerConfig{
+		Enabled:     true,
+		MinRequests: 1,
+		OpenTimeout: time.Minute,
+	}})
+	c := NewPrometheusCollector(r)
+
+	m.Close()
+	for r.CircuitState() != CircuitOpen {
+		r.Universal.Get(ctx, "user:1")
+	}
+	before := counterValue(t, c, "get", "user", resultError)
+
+	if err := r.Universal.Get(ctx, "user:1").Err(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Get = %v, want ErrCircuitOpen", err)
+	}
+	if got := counterValue(t, c, "get", "user", resultError); got != before+1 {
+		t.Fatalf("errors counted = %v, want %v", got, before+1)
+	}
+}
+
+func counterValue(t *testing.T, c *PrometheusCollector, labels ...string) float64 {
+	t.Helper()
+	var metric dto.Metric
+	if err := c.requests.WithLabelValues(labels...).Write(&metric); err != nil {
+		t.Fatal(err)
+	}
+	return metric.GetCounter().GetValue()
+}
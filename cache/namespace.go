@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Key returns the Redis key holding the cached value of key, prefixed with the namespace and schema version.
+// Only needed when accessing cached values through Client or Universal directly.
+func (r *RedisCache) Key(key string) string {
+	prefix := r.NamespaceKey("")
+	if r.cfg.SchemaVersion > 0 {
+		prefix += "v" + strconv.Itoa(r.cfg.SchemaVersion) + ":"
+	}
+	return prefix + key
+}
+
+// NamespaceKey returns key prefixed with the namespace only. It is used for coordination state such
+// as locks and rate limits, which must stay shared between replicas running different schema versions.
+func (r *RedisCache) NamespaceKey(key string) string {
+	if r.cfg.Namespace == "" {
+		return key
+	}
+	return r.cfg.Namespace + ":" + key
+}
+
+// SetTypeVersion sets the version of the layout of T, written alongside every value of type T.
+// Values written with an older version are then reported as misses and lazily deleted, so bump
+// it whenever a change of T breaks decoding of already cached values.
+// Values written with a newer version, during a rolling deploy, are reported as misses but kept.
+func SetTypeVersion[T any](r *RedisCache, version uint64) {
+	r.typeVersions.Store(reflect.TypeFor[T](), version)
+}
+
+func (r *RedisCache) typeVersion(t reflect.Type) uint64 {
+	if v, ok := r.typeVersions.Load(t); ok {
+		return v.(uint64)
+	}
+	return 0
+}
+
+// decodeMiss turns the decode errors of values that read as missing into redis.Nil: tombstones and
+// values written with another type version. Values written with an older version are deleted,
+// unless they were overwritten since.
+func (r *RedisCache) decodeMiss(ctx context.Context, key string, data []byte, err error) error {
+	if err == errTombstone {
+		return redis.Nil
+	}
+	older, ok := isStale(err)
+	if !ok {
+		return err
+	}
+	if older {
+		r.deleteIfUnchanged(ctx, key, data)
+	}
+	return redis.Nil
+}
+
+// deleteIfUnchanged deletes key when it still holds data. Failures are ignored, the entry is only
+// unreachable garbage that expires or gets overwritten eventually.
+func (r *RedisCache) deleteIfUnchanged(ctx context.Context, key string, data []byte) {
+	ctx, cancel := r.writeContext(context.WithoutCancel(ctx))
+	defer cancel()
+	releaseLockScript.Run(ctx, r.Universal, []string{key}, data)
+}
+
+// staleVersionError is returned by decode for values written with another version of their type.
+type staleVersionError struct {
+	// older is set when the value was written with an older version than the current one.
+	older bool
+}
+
+func (e *staleVersionError) Error() string {
+	return "cache: value written with another type version"
+}
@@ -0,0 +1,226 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+type NearCacheConfig struct {
+	// MaxEntries bounds the number of values kept in memory.
+	MaxEntries int
+	// Policy selects which entry is evicted when MaxEntries is reached.
+	Policy EvictionPolicy
+	// TTL is how long a value is kept in memory. It is capped by the Redis TTL of the key.
+	TTL time.Duration
+	// Channel is the Redis pub/sub channel used to broadcast invalidations between replicas.
+	// It is prefixed with the namespace of the cache.
+	Channel string
+}
+
+// withNearCacheDefaults fills in missing config fields with sane defaults.
+func withNearCacheDefaults(cfg *NearCacheConfig) {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 10000
+	}
+	if cfg.Policy == "" {
+		cfg.Policy = EvictionLRU
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 30 * time.Second
+	}
+	if cfg.Channel == "" {
+		cfg.Channel = "cache:invalidate"
+	}
+}
+
+// NearCache keeps recently read values in process memory in front of a RedisCache.
+// Once attached, every SetContext, SetNXContext, ExpireContext and DeleteContext call on the
+// RedisCache drops the local copy and broadcasts an invalidation to the other replicas.
+type NearCache struct {
+	redisCache *RedisCache
+	cfg        NearCacheConfig
+	local      *localCache
+	origin     string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewNearCache attaches a near cache to r and starts listening for invalidations.
+// The listener stops when the near cache or r is closed.
+func NewNearCache(r *RedisCache, cfg NearCacheConfig) (*NearCache, error) {
+	withNearCacheDefaults(&cfg)
+	if cfg.Policy != EvictionLRU && cfg.Policy != EvictionLFU {
+		return nil, fmt.Errorf("cache: unknown eviction policy %q", string(cfg.Policy))
+	}
+
+	r.nearMu.Lock()
+	defer r.nearMu.Unlock()
+	if r.near.Load() != nil {
+		return nil, errors.New("cache: a near cache is already attached")
+	}
+
+	cfg.Channel = r.NamespaceKey(cfg.Channel)
+	if !r.trackSubscriber() {
+		return nil, redis.ErrClosed
+	}
+
+	ctx, cancel := context.WithCancel(r.Ctx)
+	nc := &NearCache{
+		redisCache: r,
+		cfg:        cfg,
+		local:      newLocalCache(cfg.MaxEntries, cfg.Policy),
+		origin:     uuid.NewString(),
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+
+	pubsub := r.Universal.Subscribe(ctx, cfg.Channel)
+	// Wait for the subscription so invalidations published after this call are not missed.
+	if _, err := pubsub.Receive(ctx); err != nil {
+		cancel()
+		pubsub.Close()
+		r.subscribers.Done()
+		return nil, err
+	}
+
+	r.near.Store(nc)
+	go nc.listen(ctx, pubsub)
+	return nc, nil
+}
+
+// Close stops listening for invalidations and detaches the near cache from its RedisCache.
+func (nc *NearCache) Close() {
+	nc.redisCache.near.CompareAndSwap(nc, nil)
+	nc.cancel()
+	<-nc.done
+	nc.local.purge()
+}
+
+// Len returns the number of values held in memory.
+func (nc *NearCache) Len() int {
+	return nc.local.len()
+}
+
+// NearGet returns the value of key from memory, falling back to Redis on a local miss.
+// It returns redis.Nil when the key does not exist.
+func NearGet[T any](ctx context.Context, nc *NearCache, key string) (T, error) {
+	var result T
+	r := nc.redisCache
+	key = r.Key(key)
+
+	if v, ok := nc.local.get(key); ok {
+		if value, ok := v.(T); ok {
+			return value, nil
+		}
+	}
+
+	readCtx, cancel := r.readContext(ctx)
+	defer cancel()
+
+	var get *redis.StringCmd
+	var pttl *redis.DurationCmd
+	_, err := r.Universal.Pipelined(readCtx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(readCtx, key)
+		pttl = pipe.PTTL(readCtx, key)
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	data, err := get.Bytes()
+	if err != nil {
+		return result, err
+	}
+	if err := r.decode(data, &result); err != nil {
+		return result, r.decodeMiss(ctx, key, data, err)
+	}
+
+	nc.local.set(key, result, nc.localTTL(pttl.Val()))
+	return result, nil
+}
+
+// NearSet stores value in Redis and keeps it in memory.
+func NearSet[T any](ctx context.Context, nc *NearCache, key string, value T, expiration time.Duration) error {
+	if err := SetContext(ctx, nc.redisCache, key, value, expiration); err != nil {
+		return err
+	}
+	nc.local.set(nc.redisCache.Key(key), value, nc.localTTL(expiration))
+	return nil
+}
+
+// NearDelete removes key from Redis and from the memory of every replica.
+func NearDelete(ctx context.Context, nc *NearCache, key string) error {
+	return DeleteContext(ctx, nc.redisCache, key)
+}
+
+// localTTL caps the configured local TTL by the remaining Redis TTL.
+// Non-positive Redis TTLs mean the key has no expiry.
+func (nc *NearCache) localTTL(redisTTL time.Duration) time.Duration {
+	if redisTTL > 0 && redisTTL < nc.cfg.TTL {
+		return redisTTL
+	}
+	return nc.cfg.TTL
+}
+
+// invalidate drops Redis keys from memory and tells the other replicas to do the same.
+func (nc *NearCache) invalidate(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		nc.local.delete(key)
+	}
+
+	ctx, cancel := nc.redisCache.writeContext(ctx)
+	defer cancel()
+
+	_, err := nc.redisCache.Universal.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Publish(ctx, nc.cfg.Channel, nc.origin+"\x00"+key)
+		}
+		return nil
+	})
+	return err
+}
+
+func (nc *NearCache) listen(ctx context.Context, pubsub *redis.PubSub) {
+	defer nc.redisCache.subscribers.Done()
+	defer close(nc.done)
+	defer pubsub.Close()
+	// Receive does not return on cancellation by itself, closing the connection interrupts it.
+	stop := context.AfterFunc(ctx, func() { pubsub.Close() })
+	defer stop()
+
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// Invalidations may have been missed while disconnected.
+			nc.local.purge()
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			// A resubscription after a reconnect, invalidations may have been missed.
+			nc.local.purge()
+		case *redis.Message:
+			origin, key, ok := strings.Cut(m.Payload, "\x00")
+			if ok && origin != nc.origin {
+				nc.local.delete(key)
+			}
+		}
+	}
+}
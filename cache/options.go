@@ -0,0 +1,24 @@
+package cache
+
+import "github.com/thanvuc/go-core-lib/log"
+
+type Option func(*options)
+
+type options struct {
+	codec  Codec
+	logger log.Logger
+}
+
+// WithCodec sets the codec used to serialize values, overriding Config.Codec.
+func WithCodec(codec Codec) Option {
+	return func(o *options) {
+		o.codec = codec
+	}
+}
+
+// WithLogger sets the logger reporting circuit breaker state changes and undecodable pub/sub messages.
+func WithLogger(logger log.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"context"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// PubSubMessage is a decoded message received by a subscription.
+type PubSubMessage[T any] struct {
+	// Channel is the channel the message was published to, without the namespace.
+	Channel string
+	// Pattern is the pattern matching Channel for subscriptions made with PSubscribe.
+	Pattern string
+	Payload T
+}
+
+// MessageHandler handles the messages of a subscription, one at a time in the order they were received.
+type MessageHandler[T any] func(ctx context.Context, msg PubSubMessage[T])
+
+// Publish encodes value with the cache codec and publishes it to channel, prefixed with the namespace.
+// It returns the number of subscribers that received the message.
+func Publish[T any](ctx context.Context, r *RedisCache, channel string, value T) (int64, error) {
+	data, err := r.encode(value)
+	if err != nil {
+		return 0, err
+	}
+
+	writeCtx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	return r.Universal.Publish(writeCtx, r.NamespaceKey(channel), data).Result()
+}
+
+// Subscriber is a running subscription started by Subscribe or PSubscribe.
+type Subscriber struct {
+	cancel context.CancelFunc
+	closed chan struct{}
+	done   chan struct{}
+}
+
+// Close unsubscribes and waits for the connection to be closed. It does not wait for a running
+// handler, so the handler may call it; use Done to wait for the handler.
+func (s *Subscriber) Close() {
+	s.cancel()
+	<-s.closed
+}
+
+// Done is closed once the subscription has ended and the handler returned, after ctx was done,
+// Close was called or the cache was closed.
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// Subscribe calls handler with the messages published to channels until ctx is done, the subscriber
+// is closed or r is closed. It returns once the subscription is active. After a lost connection,
+// the subscription is restored automatically; messages published meanwhile are lost.
+// Messages that cannot be decoded into T are logged and skipped.
+func Subscribe[T any](ctx context.Context, r *RedisCache, handler MessageHandler[T], channels ...string) (*Subscriber, error) {
+	names := make([]string, len(channels))
+	for i, channel := range channels {
+		names[i] = r.NamespaceKey(channel)
+	}
+	return subscribe(ctx, r, handler, r.Universal.Subscribe, names)
+}
+
+// PSubscribe is like Subscribe for channels matching the glob-style patterns.
+func PSubscribe[T any](ctx context.Context, r *RedisCache, handler MessageHandler[T], patterns ...string) (*Subscriber, error) {
+	names := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		names[i] = r.NamespaceKey(pattern)
+	}
+	return subscribe(ctx, r, handler, r.Universal.PSubscribe, names)
+}
+
+func subscribe[T any](ctx context.Context, r *RedisCache, handler MessageHandler[T], open func(ctx context.Context, names ...string) *redis.PubSub, names []string) (*Subscriber, error) {
+	if !r.trackSubscriber() {
+		return nil, redis.ErrClosed
+	}
+
+	// The subscription ends with the caller context or with the cache.
+	subCtx, cancel := context.WithCancel(r.Ctx)
+	stop := context.AfterFunc(ctx, cancel)
+
+	pubsub := open(subCtx, names...)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		stop()
+		cancel()
+		pubsub.Close()
+		r.subscribers.Done()
+		return nil, err
+	}
+
+	s := &Subscriber{cancel: cancel, closed: make(chan struct{}), done: make(chan struct{})}
+	prefix := r.NamespaceKey("")
+
+	// The connection is closed as soon as the subscription ends, without waiting for the handler,
+	// so that closing from the handler does not deadlock.
+	go func() {
+		defer r.subscribers.Done()
+		defer close(s.closed)
+		<-subCtx.Done()
+		pubsub.Close()
+	}()
+
+	go func() {
+		defer close(s.done)
+		defer stop()
+		defer cancel()
+
+		// The channel pings the connection, reconnects and subscribes again when it is lost.
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-subCtx.Done():
+				return
+			case m, ok := <-messages:
+				if !ok {
+					return
+				}
+				var payload T
+				if err := r.decode([]byte(m.Payload), &payload); err != nil {
+					if r.logger != nil {
+						r.logger.Warn("Failed to decode pub/sub message", "",
+							zap.String("channel", m.Channel), zap.Error(err))
+					}
+					continue
+				}
+				handler(subCtx, PubSubMessage[T]{
+					Channel: strings.TrimPrefix(m.Channel, prefix),
+					Pattern: strings.TrimPrefix(m.Pattern, prefix),
+					Payload: payload,
+				})
+			}
+		}
+	}()
+	return s, nil
+}
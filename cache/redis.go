@@ -2,64 +2,290 @@ package cache
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/thanvuc/go-core-lib/log"
+	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 )
 
 type RedisCache struct {
+	// Client is the Redis client in standalone and sentinel mode, and nil in cluster mode.
 	Client *redis.Client
+	// Universal is the client of any configured mode: a *redis.Client, failover *redis.Client or *redis.ClusterClient.
+	Universal redis.UniversalClient
+	// Deprecated: Ctx is only used by the functions without a context parameter.
+	// Pass a request context to the *Context functions instead.
 	Ctx    context.Context
 	Cancel context.CancelFunc
+
+	cfg           Config
+	codec         Codec
+	compressionID uint8
+	loads         singleflight.Group
+	nearMu        sync.Mutex
+	near          atomic.Pointer[NearCache]
+	typeVersions  sync.Map
+	breaker       *circuitBreaker
+	fallback      *localCache
+	logger        log.Logger
+
+	// subscribers counts the open pub/sub connections, which Close waits for.
+	subscribers sync.WaitGroup
+	closeMu     sync.Mutex
+	closed      bool
+}
+
+// NewRedisCache creates a Redis cache from cfg. Invalid codec, compression or TTL jitter settings fall
+// back to JSON without compression or jitter, which other replicas may not read, and are logged on the
+// logger set with WithLogger or on a default one. Use New to get an error instead.
+// It panics when the topology or TLS settings are invalid, which have no safe default.
+func NewRedisCache(cfg Config, opts ...Option) *RedisCache {
+	withDefaults(&cfg)
+	o := newOptions(opts)
+
+	codec, compressionID, err := encodingSettings(cfg, o)
+	if err != nil {
+		logger := o.logger
+		if logger == nil {
+			logger = log.NewLogger(log.Config{})
+		}
+		logger.Error("Invalid cache encoding settings, falling back to the defaults", "", zap.Error(err))
+		codec, compressionID, cfg.TTLJitter = JSONCodec, 0, 0
+	}
+
+	r, err := newRedisCache(cfg, o, codec, compressionID)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// New creates a Redis cache from cfg, validating the topology, TLS, codec and compression settings.
+func New(cfg Config, opts ...Option) (*RedisCache, error) {
+	withDefaults(&cfg)
+	o := newOptions(opts)
+
+	codec, compressionID, err := encodingSettings(cfg, o)
+	if err != nil {
+		return nil, err
+	}
+	return newRedisCache(cfg, o, codec, compressionID)
+}
+
+func newOptions(opts []Option) options {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
 }
 
-func NewRedisCache(cfg Config) *RedisCache {
+// encodingSettings validates how values are encoded and returns the codec and compression to use.
+func encodingSettings(cfg Config, o options) (Codec, uint8, error) {
+	codec := o.codec
+	if codec == nil {
+		var err error
+		if codec, err = codecByName(cfg.Codec); err != nil {
+			return nil, 0, err
+		}
+	}
+	if id := codec.ID(); id == 0 || id > headerCodecMask {
+		return nil, 0, fmt.Errorf("cache: codec %q has invalid id %d", codec.Name(), id)
+	}
+
+	if cfg.TTLJitter < 0 || cfg.TTLJitter >= 1 {
+		return nil, 0, fmt.Errorf("cache: TTL jitter %v is not in [0, 1)", cfg.TTLJitter)
+	}
+
+	compressionID, err := cfg.Compression.id()
+	if err != nil {
+		return nil, 0, err
+	}
+	return codec, compressionID, nil
+}
+
+func newRedisCache(cfg Config, o options, codec Codec, compressionID uint8) (*RedisCache, error) {
+	rdb, err := newUniversalClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
-	rdb := redis.NewClient(&redis.Options{
-		Addr:         cfg.Addr,
-		Password:     cfg.Password,
-		DB:           cfg.DB,
-		PoolSize:     cfg.PoolSize,
-		MinIdleConns: cfg.MinIdle,
-	})
+	client, _ := rdb.(*redis.Client)
+	r := &RedisCache{
+		Client:        client,
+		Universal:     rdb,
+		Ctx:           ctx,
+		Cancel:        cancel,
+		cfg:           cfg,
+		codec:         codec,
+		compressionID: compressionID,
+		logger:        o.logger,
+	}
+	if cfg.CircuitBreaker.Enabled {
+		r.breaker = newCircuitBreaker(cfg.CircuitBreaker, o.logger)
+		rdb.AddHook(r.breaker)
+		if cfg.CircuitBreaker.FallbackEntries > 0 {
+			r.fallback = newLocalCache(cfg.CircuitBreaker.FallbackEntries, EvictionLRU)
+		}
+	}
+	return r, nil
+}
+
+// readContext applies the default read timeout when ctx has no deadline.
+func (r *RedisCache) readContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, r.cfg.ReadOperationTimeout)
+}
+
+// writeContext applies the default write timeout when ctx has no deadline.
+func (r *RedisCache) writeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, r.cfg.WriteOperationTimeout)
+}
 
-	return &RedisCache{
-		Client: rdb,
-		Ctx:    ctx,
-		Cancel: cancel,
+// ReadContext applies the default read timeout of the cache when ctx has no deadline,
+// for commands sent through Client or Universal directly.
+func (r *RedisCache) ReadContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return r.readContext(ctx)
+}
+
+// WriteContext applies the default write timeout of the cache when ctx has no deadline,
+// for commands sent through Client or Universal directly.
+func (r *RedisCache) WriteContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return r.writeContext(ctx)
+}
+
+// invalidateNear drops keys from the attached near cache, if any, on every replica.
+// It is best effort: it runs after the write was applied, so a failed broadcast is only logged and
+// the other replicas serve their copy until its near cache TTL.
+func (r *RedisCache) invalidateNear(ctx context.Context, keys ...string) {
+	r.forgetFallback(keys...)
+
+	nc := r.near.Load()
+	if nc == nil {
+		return
+	}
+	if err := nc.invalidate(ctx, keys...); err != nil && r.logger != nil {
+		r.logger.Warn("Failed to broadcast near cache invalidation", "",
+			zap.Int("keys", len(keys)), zap.Error(err))
 	}
 }
 
-func Set[T any](r *RedisCache, key string, value T, expiration time.Duration) error {
-	data, err := json.Marshal(value)
+// jitter shortens a positive expiration by a random fraction of up to TTLJitter,
+// so that keys written together do not all expire at the same time.
+func (r *RedisCache) jitter(expiration time.Duration) time.Duration {
+	if r.cfg.TTLJitter <= 0 || expiration <= 0 {
+		return expiration
+	}
+	jittered := expiration - time.Duration(rand.Float64()*r.cfg.TTLJitter*float64(expiration))
+	return max(jittered, time.Millisecond)
+}
+
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// SetContext stores value under key using the cache codec.
+func SetContext[T any](ctx context.Context, r *RedisCache, key string, value T, expiration time.Duration) error {
+	return r.setEntry(ctx, key, value, expiration, 0)
+}
+
+// setEntry stores value under key with a jittered expiration, marking it stale after softTTL when positive.
+func (r *RedisCache) setEntry(ctx context.Context, key string, value any, expiration, softTTL time.Duration) error {
+	key = r.Key(key)
+
+	var softExpiry time.Time
+	if softTTL > 0 {
+		softExpiry = time.Now().Add(r.jitter(softTTL))
+	}
+	data, err := r.encodeEntry(value, softExpiry)
 	if err != nil {
 		return err
 	}
-	return r.Client.Set(r.Ctx, key, data, expiration).Err()
+
+	writeCtx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	if err := r.Universal.Set(writeCtx, key, data, r.jitter(expiration)).Err(); err != nil {
+		if errors.Is(err, ErrCircuitOpen) {
+			r.rememberFallback(key, value, expiration)
+		}
+		return err
+	}
+	r.invalidateNear(ctx, key)
+	r.rememberFallback(key, value, expiration)
+	return nil
 }
 
-func Get[T any](r *RedisCache, key string) (T, error) {
+// setTombstone records for expiration that key was not found by its loader.
+func (r *RedisCache) setTombstone(ctx context.Context, key string, expiration time.Duration) error {
+	key = r.Key(key)
+
+	writeCtx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	if err := r.Universal.Set(writeCtx, key, tombstone, r.jitter(expiration)).Err(); err != nil {
+		return err
+	}
+	r.invalidateNear(ctx, key)
+	return nil
+}
+
+// GetContext reads the value stored under key. It returns redis.Nil when the key does not exist.
+func GetContext[T any](ctx context.Context, r *RedisCache, key string) (T, error) {
+	value, _, err := getEntry[T](ctx, r, key)
+	if err == errTombstone {
+		err = redis.Nil
+	}
+	return value, err
+}
+
+// getEntry reads the value stored under key and the time after which it is stale, zero when it has none.
+// It returns errTombstone when negative caching recorded that key was not found.
+func getEntry[T any](ctx context.Context, r *RedisCache, key string) (T, time.Time, error) {
 	var result T
+	key = r.Key(key)
 
-	data, err := r.Client.Get(r.Ctx, key).Result()
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
+
+	data, err := r.Universal.Get(ctx, key).Bytes()
+	if errors.Is(err, ErrCircuitOpen) {
+		result, err = fallbackValue[T](r, key)
+		return result, time.Time{}, err
+	}
 	if err != nil {
-		return result, err
+		return result, time.Time{}, err
 	}
 
-	if err := json.Unmarshal([]byte(data), &result); err != nil {
-		return result, err
+	softExpiry, err := r.decodeEntry(data, &result)
+	if err == errTombstone {
+		return result, time.Time{}, err
+	}
+	if err != nil {
+		var zero T
+		return zero, time.Time{}, r.decodeMiss(ctx, key, data, err)
 	}
 
-	return result, nil
+	r.rememberFallback(key, result, 0)
+	return result, softExpiry, nil
 }
 
-func GetAndSet[T any](r *RedisCache, key string, value T, expiration time.Duration) (T, error) {
+// GetAndSetContext returns the value stored under key, storing value first when the key does not exist.
+func GetAndSetContext[T any](ctx context.Context, r *RedisCache, key string, value T, expiration time.Duration) (T, error) {
 	var result T
 
-	existing, err := Get[T](r, key)
+	existing, err := GetContext[T](ctx, r, key)
 	if err == nil {
 		return existing, nil
 	}
@@ -68,59 +294,160 @@ func GetAndSet[T any](r *RedisCache, key string, value T, expiration time.Durati
 		return result, err
 	}
 
-	if err := Set(r, key, value, expiration); err != nil {
+	if err := SetContext(ctx, r, key, value, expiration); err != nil {
 		return result, err
 	}
 
 	return value, nil
 }
 
-func Exists(r *RedisCache, key string) (bool, error) {
-	count, err := r.Client.Exists(r.Ctx, key).Result()
+// ExistsContext reports whether key exists.
+func ExistsContext(ctx context.Context, r *RedisCache, key string) (bool, error) {
+	key = r.Key(key)
+
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
+
+	count, err := r.Universal.Exists(ctx, key).Result()
 	if err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
-func Delete(r *RedisCache, key string) error {
-	return r.Client.Del(r.Ctx, key).Err()
-}
+// DeleteContext removes key.
+func DeleteContext(ctx context.Context, r *RedisCache, key string) error {
+	key = r.Key(key)
+	r.forgetFallback(key)
 
-func (r *RedisCache) Close(wg *sync.WaitGroup) error {
-	r.Cancel()
-	defer wg.Done()
-	return r.Client.Close()
-}
+	writeCtx, cancel := r.writeContext(ctx)
+	defer cancel()
 
-func (r *RedisCache) Ping() error {
-	return r.Client.Ping(r.Ctx).Err()
+	if err := r.Universal.Del(writeCtx, key).Err(); err != nil {
+		return err
+	}
+	r.invalidateNear(ctx, key)
+	return nil
 }
 
-func SetNX[T any](r *RedisCache, key string, value T, expiration time.Duration) (bool, error) {
-	data, err := json.Marshal(value)
+// SetNXContext stores value under key only when the key does not exist yet.
+func SetNXContext[T any](ctx context.Context, r *RedisCache, key string, value T, expiration time.Duration) (bool, error) {
+	key = r.Key(key)
+
+	data, err := r.encode(value)
 	if err != nil {
 		return false, err
 	}
-	ok, err := r.Client.SetNX(r.Ctx, key, data, expiration).Result()
+
+	writeCtx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	ok, err := r.Universal.SetNX(writeCtx, key, data, expiration).Result()
 	if err != nil {
 		return false, err
 	}
+	if ok {
+		r.invalidateNear(ctx, key)
+	}
 	return ok, nil
 }
 
-func Expire(r *RedisCache, key string, expiration time.Duration) (bool, error) {
-	ok, err := r.Client.Expire(r.Ctx, key, expiration).Result()
+// ExpireContext sets the time to live of key. It returns false when the key does not exist.
+func ExpireContext(ctx context.Context, r *RedisCache, key string, expiration time.Duration) (bool, error) {
+	key = r.Key(key)
+
+	writeCtx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	ok, err := r.Universal.Expire(writeCtx, key, expiration).Result()
 	if err != nil {
 		return false, err
 	}
+	if ok {
+		r.invalidateNear(ctx, key)
+	}
 	return ok, nil
 }
 
-func RenewTTL(r *RedisCache, key string, expiration time.Duration) (bool, error) {
-	ok, err := r.Client.Expire(r.Ctx, key, expiration).Result()
-	if err != nil {
-		return false, err
+// RenewTTLContext is an alias of ExpireContext.
+func RenewTTLContext(ctx context.Context, r *RedisCache, key string, expiration time.Duration) (bool, error) {
+	return ExpireContext(ctx, r, key, expiration)
+}
+
+// PingContext checks the connection to Redis.
+func (r *RedisCache) PingContext(ctx context.Context) error {
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
+
+	return r.Universal.Ping(ctx).Err()
+}
+
+func (r *RedisCache) Close(wg *sync.WaitGroup) error {
+	defer wg.Done()
+
+	r.closeMu.Lock()
+	r.closed = true
+	r.closeMu.Unlock()
+
+	// Subscriptions stop with the cache context, wait for their connections before closing the pool.
+	r.Cancel()
+	r.subscribers.Wait()
+	return r.Universal.Close()
+}
+
+// trackSubscriber registers a pub/sub connection that Close waits for, and reports false when r is closed.
+// The connection must call subscribers.Done once closed.
+func (r *RedisCache) trackSubscriber() bool {
+	r.closeMu.Lock()
+	defer r.closeMu.Unlock()
+	if r.closed {
+		return false
 	}
-	return ok, nil
+	r.subscribers.Add(1)
+	return true
+}
+
+// Deprecated: Use SetContext instead.
+func Set[T any](r *RedisCache, key string, value T, expiration time.Duration) error {
+	return SetContext(r.Ctx, r, key, value, expiration)
+}
+
+// Deprecated: Use GetContext instead.
+func Get[T any](r *RedisCache, key string) (T, error) {
+	return GetContext[T](r.Ctx, r, key)
+}
+
+// Deprecated: Use GetAndSetContext instead.
+func GetAndSet[T any](r *RedisCache, key string, value T, expiration time.Duration) (T, error) {
+	return GetAndSetContext(r.Ctx, r, key, value, expiration)
+}
+
+// Deprecated: Use ExistsContext instead.
+func Exists(r *RedisCache, key string) (bool, error) {
+	return ExistsContext(r.Ctx, r, key)
+}
+
+// Deprecated: Use DeleteContext instead.
+func Delete(r *RedisCache, key string) error {
+	return DeleteContext(r.Ctx, r, key)
+}
+
+// Deprecated: Use PingContext instead.
+func (r *RedisCache) Ping() error {
+	return r.PingContext(r.Ctx)
+}
+
+// Deprecated: Use SetNXContext instead.
+func SetNX[T any](r *RedisCache, key string, value T, expiration time.Duration) (bool, error) {
+	return SetNXContext(r.Ctx, r, key, value, expiration)
+}
+
+// Deprecated: Use ExpireContext instead.
+func Expire(r *RedisCache, key string, expiration time.Duration) (bool, error) {
+	return ExpireContext(r.Ctx, r, key, expiration)
+}
+
+// Deprecated: Use RenewTTLContext instead.
+func RenewTTL(r *RedisCache, key string, expiration time.Duration) (bool, error) {
+	return RenewTTLContext(r.Ctx, r, key, expiration)
 }
@@ -0,0 +1,282 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultScanCount = 100
+
+type ScanOption func(*scanOptions)
+
+type scanOptions struct {
+	count       int64
+	batchSize   int
+	rate        int
+	allVersions bool
+}
+
+// WithScanCount sets how many keys each SCAN call examines, 100 by default.
+func WithScanCount(count int64) ScanOption {
+	return func(o *scanOptions) {
+		o.count = count
+	}
+}
+
+// WithBatchSize sets how many keys DeleteByPattern unlinks per round trip, 100 by default.
+func WithBatchSize(size int) ScanOption {
+	return func(o *scanOptions) {
+		o.batchSize = size
+	}
+}
+
+// WithKeysPerSecond bounds how many keys are visited per second, 1000 by default.
+// Zero or a negative value removes the limit.
+func WithKeysPerSecond(rate int) ScanOption {
+	return func(o *scanOptions) {
+		o.rate = rate
+	}
+}
+
+// WithAllVersions matches the keys of every schema version instead of the current one only,
+// for example to purge the values left behind by a previous SchemaVersion. ScanKeys then passes
+// keys with their "v<N>:" prefix. Keys without a version prefix are only matched when SchemaVersion
+// is 0, as they cannot be told apart from the locks and queues stored under the namespace.
+func WithAllVersions() ScanOption {
+	return func(o *scanOptions) {
+		o.allVersions = true
+	}
+}
+
+func newScanOptions(opts []ScanOption) scanOptions {
+	o := scanOptions{count: defaultScanCount, batchSize: 100, rate: 1000}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.count <= 0 {
+		o.count = defaultScanCount
+	}
+	if o.batchSize <= 0 {
+		o.batchSize = 100
+	}
+	return o
+}
+
+// ScanKeys calls fn for every cached key of the current schema version matching the glob-style
+// pattern, without the namespace and version, and returns the number of keys visited. It iterates
+// with SCAN, on every master in cluster mode, so it never blocks Redis like KEYS; keys created or
+// deleted meanwhile may be missed or visited twice. Returning an error from fn stops the scan.
+func (r *RedisCache) ScanKeys(ctx context.Context, pattern string, fn func(key string) error, opts ...ScanOption) (int, error) {
+	o := newScanOptions(opts)
+	match, filter := r.scanPattern(pattern, o.allVersions)
+	pace := newPacer(o.rate)
+
+	visited := 0
+	err := r.scan(ctx, match, o.count, func(key string) error {
+		key, ok := filter(key)
+		if !ok {
+			return nil
+		}
+		if err := pace.wait(ctx); err != nil {
+			return err
+		}
+		visited++
+		return fn(key)
+	})
+	return visited, err
+}
+
+// DeleteByPattern removes every cached key of the current schema version matching the glob-style
+// pattern with batched UNLINK, which frees memory in the background, and returns the number of keys
+// deleted. Like ScanKeys it does not block Redis and is paced by WithKeysPerSecond.
+func (r *RedisCache) DeleteByPattern(ctx context.Context, pattern string, opts ...ScanOption) (int, error) {
+	o := newScanOptions(opts)
+	match, filter := r.scanPattern(pattern, o.allVersions)
+	pace := newPacer(o.rate)
+
+	deleted := 0
+	batch := make([]string, 0, o.batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := r.unlink(ctx, batch)
+		deleted += n
+		batch = batch[:0]
+		return err
+	}
+
+	err := r.scan(ctx, match, o.count, func(key string) error {
+		if _, ok := filter(key); !ok {
+			return nil
+		}
+		if err := pace.wait(ctx); err != nil {
+			return err
+		}
+		batch = append(batch, key)
+		if len(batch) < o.batchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return deleted, err
+	}
+	return deleted, flush()
+}
+
+// unlink removes Redis keys and returns how many existed. Keys are unlinked one by one in a
+// pipeline since a multi-key UNLINK fails across cluster slots.
+func (r *RedisCache) unlink(ctx context.Context, keys []string) (int, error) {
+	writeCtx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	cmds := make([]*redis.IntCmd, len(keys))
+	_, err := r.Universal.Pipelined(writeCtx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Unlink(writeCtx, key)
+		}
+		return nil
+	})
+
+	n := 0
+	for _, cmd := range cmds {
+		n += int(cmd.Val())
+	}
+	if err != nil {
+		return n, err
+	}
+	r.invalidateNear(ctx, keys...)
+	return n, nil
+}
+
+// scanPattern returns the SCAN pattern of the keys matching pattern and a filter turning a scanned
+// Redis key into the key reported to the caller, false when it does not actually match.
+func (r *RedisCache) scanPattern(pattern string, allVersions bool) (string, func(key string) (string, bool)) {
+	if !allVersions {
+		prefix := r.Key("")
+		return escapeGlob(prefix) + pattern, func(key string) (string, bool) {
+			return strings.TrimPrefix(key, prefix), true
+		}
+	}
+
+	// The version is matched here, a glob cannot express "v" followed by digits only.
+	prefix := r.NamespaceKey("")
+	return escapeGlob(prefix) + "*" + pattern, func(key string) (string, bool) {
+		key = strings.TrimPrefix(key, prefix)
+		if rest, ok := trimVersion(key); ok {
+			return key, globMatch(pattern, rest)
+		}
+		return key, r.cfg.SchemaVersion == 0 && globMatch(pattern, key)
+	}
+}
+
+// trimVersion removes the "v<N>:" schema version prefix of key and reports whether it had one.
+func trimVersion(key string) (string, bool) {
+	i := 1
+	for i < len(key) && key[i] >= '0' && key[i] <= '9' {
+		i++
+	}
+	if len(key) == 0 || key[0] != 'v' || i == 1 || i == len(key) || key[i] != ':' {
+		return key, false
+	}
+	return key[i+1:], true
+}
+
+// escapeGlob escapes the characters of s that are special in Redis glob-style patterns.
+func escapeGlob(s string) string {
+	if !strings.ContainsAny(s, `*?[]\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// pacer spaces calls so that no more than rate happen per second on average.
+type pacer struct {
+	interval time.Duration
+	next     time.Time
+}
+
+func newPacer(rate int) *pacer {
+	if rate <= 0 {
+		return &pacer{}
+	}
+	return &pacer{interval: time.Second / time.Duration(rate)}
+}
+
+func (p *pacer) wait(ctx context.Context) error {
+	if p.interval == 0 {
+		return nil
+	}
+	now := time.Now()
+	if p.next.Before(now) {
+		p.next = now
+	}
+	delay := p.next.Sub(now)
+	p.next = p.next.Add(p.interval)
+	// Short delays are accumulated into longer sleeps rather than a timer per key.
+	if delay < 10*time.Millisecond {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// scan calls fn for every Redis key matching pattern, on every master in cluster mode.
+// count is the number of keys SCAN examines per call.
+func (r *RedisCache) scan(ctx context.Context, pattern string, count int64, fn func(key string) error) error {
+	scanNode := func(ctx context.Context, client redis.Cmdable) error {
+		var cursor uint64
+		for {
+			readCtx, cancel := r.readContext(ctx)
+			keys, next, err := client.Scan(readCtx, cursor, pattern, count).Result()
+			cancel()
+			if err != nil {
+				return err
+			}
+			for _, key := range keys {
+				if err := fn(key); err != nil {
+					return err
+				}
+			}
+			if next == 0 {
+				return nil
+			}
+			cursor = next
+		}
+	}
+
+	if cluster, ok := r.Universal.(*redis.ClusterClient); ok {
+		// Masters are scanned concurrently, fn is not.
+		var mu sync.Mutex
+		serialFn := fn
+		fn = func(key string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			return serialFn(key)
+		}
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return scanNode(ctx, node)
+		})
+	}
+	return scanNode(ctx, r.Universal)
+}
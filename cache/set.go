@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SetOf is a typed handle on a Redis set whose members are encoded with the cache codec.
+// Members are compared by their encoded bytes, so T should encode deterministically.
+type SetOf[T any] struct {
+	structure
+}
+
+// NewSet binds a typed set handle to key.
+func NewSet[T any](r *RedisCache, key string) *SetOf[T] {
+	s := &SetOf[T]{structure{r: r, key: r.Key(key)}}
+	s.evict = func(ctx context.Context, stale []any) error {
+		ctx, cancel := r.writeContext(ctx)
+		defer cancel()
+		return r.Universal.SRem(ctx, s.key, stale...).Err()
+	}
+	return s
+}
+
+// Add inserts members and returns how many were not already present.
+func (s *SetOf[T]) Add(ctx context.Context, members ...T) (int64, error) {
+	encoded, err := encodeAll(s.r, members)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := s.r.writeContext(ctx)
+	defer cancel()
+	return s.r.Universal.SAdd(ctx, s.key, encoded...).Result()
+}
+
+// Remove deletes members and returns how many were present.
+func (s *SetOf[T]) Remove(ctx context.Context, members ...T) (int64, error) {
+	encoded, err := encodeAll(s.r, members)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := s.r.writeContext(ctx)
+	defer cancel()
+	return s.r.Universal.SRem(ctx, s.key, encoded...).Result()
+}
+
+// Contains reports whether member is in the set.
+func (s *SetOf[T]) Contains(ctx context.Context, member T) (bool, error) {
+	data, err := s.r.encode(member)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := s.r.readContext(ctx)
+	defer cancel()
+	return s.r.Universal.SIsMember(ctx, s.key, data).Result()
+}
+
+// Members returns every member of the set, in no particular order.
+func (s *SetOf[T]) Members(ctx context.Context) ([]T, error) {
+	ctx, cancel := s.r.readContext(ctx)
+	defer cancel()
+
+	data, err := s.r.Universal.SMembers(ctx, s.key).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeAll[T](ctx, s.structure, data)
+}
+
+// Pop removes and returns a random member, or redis.Nil when the set is empty.
+func (s *SetOf[T]) Pop(ctx context.Context) (T, error) {
+	for {
+		popCtx, cancel := s.r.writeContext(ctx)
+		data, err := s.r.Universal.SPop(popCtx, s.key).Result()
+		cancel()
+
+		var value T
+		if err != nil {
+			return value, err
+		}
+		err = s.r.decode([]byte(data), &value)
+		older, stale := isStale(err)
+		if stale && older {
+			// The older member is removed by the pop, try another one.
+			continue
+		}
+		if stale {
+			// Written by a newer replica, put it back for the replicas that can read it.
+			addCtx, cancel := s.r.writeContext(context.WithoutCancel(ctx))
+			err := s.r.Universal.SAdd(addCtx, s.key, data).Err()
+			cancel()
+			var zero T
+			if err != nil {
+				return zero, err
+			}
+			return zero, redis.Nil
+		}
+		return value, err
+	}
+}
+
+// Random returns up to count distinct random members without removing them.
+func (s *SetOf[T]) Random(ctx context.Context, count int64) ([]T, error) {
+	ctx, cancel := s.r.readContext(ctx)
+	defer cancel()
+
+	data, err := s.r.Universal.SRandMemberN(ctx, s.key, count).Result()
+	if err != nil {
+		return nil, err
+	}
+	return decodeAll[T](ctx, s.structure, data)
+}
+
+// Len returns the number of members.
+func (s *SetOf[T]) Len(ctx context.Context) (int64, error) {
+	ctx, cancel := s.r.readContext(ctx)
+	defer cancel()
+	return s.r.Universal.SCard(ctx, s.key).Result()
+}
+
+// Scan returns a page of members starting at cursor, and the cursor of the next page.
+// Iteration is complete when the returned cursor is 0.
+func (s *SetOf[T]) Scan(ctx context.Context, cursor uint64, count int64) ([]T, uint64, error) {
+	ctx, cancel := s.r.readContext(ctx)
+	defer cancel()
+
+	data, next, err := s.r.Universal.SScan(ctx, s.key, cursor, "", count).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	members, err := decodeAll[T](ctx, s.structure, data)
+	return members, next, err
+}
@@ -0,0 +1,262 @@
+package cache
+
+import (
+	"context"
+	"math"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ScoredMember is a member of a sorted set with its score.
+type ScoredMember[T any] struct {
+	Member T
+	Score  float64
+}
+
+// SortedSet is a typed handle on a Redis sorted set whose members are encoded with the cache codec.
+// Members are compared by their encoded bytes, so T should encode deterministically.
+type SortedSet[T any] struct {
+	structure
+}
+
+// NewSortedSet binds a typed sorted set handle to key.
+func NewSortedSet[T any](r *RedisCache, key string) *SortedSet[T] {
+	z := &SortedSet[T]{structure{r: r, key: r.Key(key)}}
+	z.evict = func(ctx context.Context, stale []any) error {
+		ctx, cancel := r.writeContext(ctx)
+		defer cancel()
+		return r.Universal.ZRem(ctx, z.key, stale...).Err()
+	}
+	return z
+}
+
+// Add inserts members or updates their score and returns how many were new.
+func (z *SortedSet[T]) Add(ctx context.Context, members ...ScoredMember[T]) (int64, error) {
+	zs := make([]redis.Z, len(members))
+	for i, m := range members {
+		data, err := z.r.encode(m.Member)
+		if err != nil {
+			return 0, err
+		}
+		zs[i] = redis.Z{Score: m.Score, Member: data}
+	}
+
+	ctx, cancel := z.r.writeContext(ctx)
+	defer cancel()
+	return z.r.Universal.ZAdd(ctx, z.key, zs...).Result()
+}
+
+// IncrBy adds delta to the score of member, inserting it when missing, and returns the new score.
+func (z *SortedSet[T]) IncrBy(ctx context.Context, member T, delta float64) (float64, error) {
+	data, err := z.r.encode(member)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := z.r.writeContext(ctx)
+	defer cancel()
+	return z.r.Universal.ZIncrBy(ctx, z.key, delta, string(data)).Result()
+}
+
+// Remove deletes members and returns how many were present.
+func (z *SortedSet[T]) Remove(ctx context.Context, members ...T) (int64, error) {
+	encoded, err := encodeAll(z.r, members)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := z.r.writeContext(ctx)
+	defer cancel()
+	return z.r.Universal.ZRem(ctx, z.key, encoded...).Result()
+}
+
+// Score returns the score of member, or redis.Nil when it is not in the set.
+func (z *SortedSet[T]) Score(ctx context.Context, member T) (float64, error) {
+	data, err := z.r.encode(member)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := z.r.readContext(ctx)
+	defer cancel()
+	return z.r.Universal.ZScore(ctx, z.key, string(data)).Result()
+}
+
+// Rank returns the 0-based position of member by ascending score, or redis.Nil when it is not in the set.
+func (z *SortedSet[T]) Rank(ctx context.Context, member T) (int64, error) {
+	data, err := z.r.encode(member)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := z.r.readContext(ctx)
+	defer cancel()
+	return z.r.Universal.ZRank(ctx, z.key, string(data)).Result()
+}
+
+// RevRank returns the 0-based position of member by descending score, or redis.Nil when it is not in the set.
+func (z *SortedSet[T]) RevRank(ctx context.Context, member T) (int64, error) {
+	data, err := z.r.encode(member)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := z.r.readContext(ctx)
+	defer cancel()
+	return z.r.Universal.ZRevRank(ctx, z.key, string(data)).Result()
+}
+
+// Range returns the members between positions start and stop, both inclusive, by ascending score.
+func (z *SortedSet[T]) Range(ctx context.Context, start, stop int64) ([]ScoredMember[T], error) {
+	ctx, cancel := z.r.readContext(ctx)
+	defer cancel()
+
+	zs, err := z.r.Universal.ZRangeWithScores(ctx, z.key, start, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+	return z.decodeZ(ctx, zs)
+}
+
+// RevRange returns the members between positions start and stop, both inclusive, by descending score,
+// for example the top of a leaderboard.
+func (z *SortedSet[T]) RevRange(ctx context.Context, start, stop int64) ([]ScoredMember[T], error) {
+	ctx, cancel := z.r.readContext(ctx)
+	defer cancel()
+
+	zs, err := z.r.Universal.ZRevRangeWithScores(ctx, z.key, start, stop).Result()
+	if err != nil {
+		return nil, err
+	}
+	return z.decodeZ(ctx, zs)
+}
+
+// Page returns the page-th page, starting at 0, of size members, by descending score when desc is set.
+func (z *SortedSet[T]) Page(ctx context.Context, page, size int64, desc bool) ([]ScoredMember[T], error) {
+	if page < 0 || size <= 0 {
+		return nil, nil
+	}
+	start := page * size
+	if desc {
+		return z.RevRange(ctx, start, start+size-1)
+	}
+	return z.Range(ctx, start, start+size-1)
+}
+
+// RangeByScore returns up to count members with a score between min and max, both inclusive,
+// by ascending score, skipping the first offset. A negative count returns every match.
+func (z *SortedSet[T]) RangeByScore(ctx context.Context, minScore, maxScore float64, offset, count int64) ([]ScoredMember[T], error) {
+	ctx, cancel := z.r.readContext(ctx)
+	defer cancel()
+
+	zs, err := z.r.Universal.ZRangeByScoreWithScores(ctx, z.key, &redis.ZRangeBy{
+		Min:    formatScore(minScore),
+		Max:    formatScore(maxScore),
+		Offset: offset,
+		Count:  count,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	return z.decodeZ(ctx, zs)
+}
+
+// PopMin removes and returns up to count members with the lowest scores.
+func (z *SortedSet[T]) PopMin(ctx context.Context, count int64) ([]ScoredMember[T], error) {
+	ctx, cancel := z.r.writeContext(ctx)
+	defer cancel()
+
+	zs, err := z.r.Universal.ZPopMin(ctx, z.key, count).Result()
+	if err != nil {
+		return nil, err
+	}
+	return z.decodePopped(ctx, zs)
+}
+
+// PopMax removes and returns up to count members with the highest scores.
+func (z *SortedSet[T]) PopMax(ctx context.Context, count int64) ([]ScoredMember[T], error) {
+	ctx, cancel := z.r.writeContext(ctx)
+	defer cancel()
+
+	zs, err := z.r.Universal.ZPopMax(ctx, z.key, count).Result()
+	if err != nil {
+		return nil, err
+	}
+	return z.decodePopped(ctx, zs)
+}
+
+// RemoveRangeByScore deletes the members with a score between min and max, both inclusive,
+// and returns how many were removed.
+func (z *SortedSet[T]) RemoveRangeByScore(ctx context.Context, minScore, maxScore float64) (int64, error) {
+	ctx, cancel := z.r.writeContext(ctx)
+	defer cancel()
+	return z.r.Universal.ZRemRangeByScore(ctx, z.key, formatScore(minScore), formatScore(maxScore)).Result()
+}
+
+// Len returns the number of members.
+func (z *SortedSet[T]) Len(ctx context.Context) (int64, error) {
+	ctx, cancel := z.r.readContext(ctx)
+	defer cancel()
+	return z.r.Universal.ZCard(ctx, z.key).Result()
+}
+
+// decodeZ decodes members, skipping and evicting those written with another version of T.
+func (z *SortedSet[T]) decodeZ(ctx context.Context, zs []redis.Z) ([]ScoredMember[T], error) {
+	members := make([]ScoredMember[T], 0, len(zs))
+	var stale []any
+	for _, m := range zs {
+		data, _ := m.Member.(string)
+		var value T
+		err := z.r.decode([]byte(data), &value)
+		if older, ok := isStale(err); ok {
+			if older {
+				stale = append(stale, data)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, ScoredMember[T]{Member: value, Score: m.Score})
+	}
+
+	if len(stale) > 0 {
+		// Eviction is best effort, stale members are skipped anyway.
+		z.evict(context.WithoutCancel(ctx), stale)
+	}
+	return members, nil
+}
+
+// decodePopped is decodeZ for popped members. Members written with a newer version of T are
+// added back, the pop must not destroy what newer replicas wrote.
+func (z *SortedSet[T]) decodePopped(ctx context.Context, zs []redis.Z) ([]ScoredMember[T], error) {
+	var newer []redis.Z
+	for _, m := range zs {
+		data, _ := m.Member.(string)
+		var value T
+		if older, ok := isStale(z.r.decode([]byte(data), &value)); ok && !older {
+			newer = append(newer, m)
+		}
+	}
+	if len(newer) > 0 {
+		addCtx, cancel := z.r.writeContext(context.WithoutCancel(ctx))
+		err := z.r.Universal.ZAdd(addCtx, z.key, newer...).Err()
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return z.decodeZ(ctx, zs)
+}
+
+// formatScore formats a score bound, mapping infinities to the -inf and +inf Redis accepts.
+func formatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "+inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
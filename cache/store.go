@@ -0,0 +1,263 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Store is the key/value and pub/sub API of the cache, implemented by RedisStore and by
+// MemoryStore for tests. Values are encoded by the store; Get decodes into dest, which must be a pointer.
+// Both implementations return redis.Nil when Get misses.
+type Store interface {
+	LeaseStore
+	Get(ctx context.Context, key string, dest any) error
+	Set(ctx context.Context, key string, value any, expiration time.Duration) error
+	// Expire sets the time to live of key, a non-positive expiration deletes it.
+	// It returns false when key does not exist.
+	Expire(ctx context.Context, key string, expiration time.Duration) (bool, error)
+	// Delete removes keys and returns how many existed.
+	Delete(ctx context.Context, keys ...string) (int, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	// Scan calls fn for every key matching the glob pattern until fn returns an error.
+	Scan(ctx context.Context, pattern string, fn func(key string) error) error
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// Leases returns where to hold leases that must not change with the schema version, such as cron locks.
+	// MemoryStore has a single key space and returns itself.
+	Leases() LeaseStore
+	// Subscribe listens to channels until the subscription is closed or ctx is done.
+	Subscribe(ctx context.Context, channels ...string) (Subscription, error)
+}
+
+// LeaseStore holds leases such as locks: values owned by one holder that expire unless renewed.
+// A Store is a LeaseStore on its own keys. RedisLeaseStore keeps leases apart from the cached values,
+// under NamespaceKey only, so that replicas on different schema versions share them.
+type LeaseStore interface {
+	// SetNX stores value only when key does not exist yet and reports whether it did.
+	SetNX(ctx context.Context, key string, value any, expiration time.Duration) (bool, error)
+	// CompareAndDelete removes key only when it holds value and reports whether it did.
+	CompareAndDelete(ctx context.Context, key string, value any) (bool, error)
+	// CompareAndExpire sets the time to live of key only when it holds value and reports whether it did.
+	CompareAndExpire(ctx context.Context, key string, value any, expiration time.Duration) (bool, error)
+}
+
+// Subscription delivers the messages published to the subscribed channels.
+type Subscription interface {
+	// Channel returns the channel of received messages, closed when the subscription ends.
+	Channel() <-chan *Message
+	Close() error
+}
+
+type Message struct {
+	Channel string
+	Payload []byte
+}
+
+// RedisStore implements Store on a RedisCache, with its codec, namespace and near cache invalidation.
+type RedisStore struct {
+	r *RedisCache
+}
+
+var _ Store = (*RedisStore)(nil)
+
+// NewRedisStore wraps r into a Store.
+func NewRedisStore(r *RedisCache) *RedisStore {
+	return &RedisStore{r: r}
+}
+
+// Cache returns the underlying RedisCache.
+func (s *RedisStore) Cache() *RedisCache {
+	return s.r
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string, dest any) error {
+	key = s.r.Key(key)
+
+	ctx, cancel := s.r.readContext(ctx)
+	defer cancel()
+
+	data, err := s.r.Universal.Get(ctx, key).Bytes()
+	if err != nil {
+		return err
+	}
+	if err := s.r.decode(data, dest); err != nil {
+		return s.r.decodeMiss(ctx, key, data, err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Set(ctx context.Context, key string, value any, expiration time.Duration) error {
+	return s.r.setEntry(ctx, key, value, expiration, 0)
+}
+
+func (s *RedisStore) SetNX(ctx context.Context, key string, value any, expiration time.Duration) (bool, error) {
+	return SetNXContext(ctx, s.r, key, value, expiration)
+}
+
+func (s *RedisStore) Expire(ctx context.Context, key string, expiration time.Duration) (bool, error) {
+	return ExpireContext(ctx, s.r, key, expiration)
+}
+
+func (s *RedisStore) Delete(ctx context.Context, keys ...string) (int, error) {
+	return MDelete(ctx, s.r, keys...)
+}
+
+func (s *RedisStore) CompareAndDelete(ctx context.Context, key string, value any) (bool, error) {
+	key = s.r.Key(key)
+	ok, err := s.r.compareAndDelete(ctx, key, value)
+	if ok {
+		s.r.invalidateNear(ctx, key)
+	}
+	return ok, err
+}
+
+func (s *RedisStore) CompareAndExpire(ctx context.Context, key string, value any, expiration time.Duration) (bool, error) {
+	key = s.r.Key(key)
+	ok, err := s.r.compareAndExpire(ctx, key, value, expiration)
+	if ok {
+		s.r.invalidateNear(ctx, key)
+	}
+	return ok, err
+}
+
+// Leases returns a RedisLeaseStore on the same cache.
+func (s *RedisStore) Leases() LeaseStore {
+	return NewRedisLeaseStore(s.r)
+}
+
+func (s *RedisStore) Exists(ctx context.Context, key string) (bool, error) {
+	return ExistsContext(ctx, s.r, key)
+}
+
+func (s *RedisStore) Scan(ctx context.Context, pattern string, fn func(key string) error) error {
+	prefix := s.r.Key("")
+	return s.r.scan(ctx, escapeGlob(prefix)+pattern, defaultScanCount, func(key string) error {
+		return fn(strings.TrimPrefix(key, prefix))
+	})
+}
+
+// Publish sends payload to channel, prefixed with the namespace.
+func (s *RedisStore) Publish(ctx context.Context, channel string, payload []byte) error {
+	ctx, cancel := s.r.writeContext(ctx)
+	defer cancel()
+	return s.r.Universal.Publish(ctx, s.r.NamespaceKey(channel), payload).Err()
+}
+
+// Subscribe listens to channels, prefixed with the namespace. It returns once the subscription is active.
+// The subscription also ends when the underlying RedisCache is closed.
+func (s *RedisStore) Subscribe(ctx context.Context, channels ...string) (Subscription, error) {
+	names := make([]string, len(channels))
+	for i, channel := range channels {
+		names[i] = s.r.NamespaceKey(channel)
+	}
+
+	if !s.r.trackSubscriber() {
+		return nil, redis.ErrClosed
+	}
+	subCtx, cancel := context.WithCancel(s.r.Ctx)
+	stop := context.AfterFunc(ctx, cancel)
+
+	pubsub := s.r.Universal.Subscribe(subCtx, names...)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		stop()
+		cancel()
+		pubsub.Close()
+		s.r.subscribers.Done()
+		return nil, err
+	}
+
+	sub := &redisSubscription{pubsub: pubsub, ch: make(chan *Message, 100)}
+	prefix := s.r.NamespaceKey("")
+	go func() {
+		defer s.r.subscribers.Done()
+		defer close(sub.ch)
+		defer stop()
+		defer cancel()
+		for {
+			select {
+			case <-subCtx.Done():
+				pubsub.Close()
+				return
+			case msg, ok := <-pubsub.Channel():
+				if !ok {
+					return
+				}
+				select {
+				case sub.ch <- &Message{Channel: strings.TrimPrefix(msg.Channel, prefix), Payload: []byte(msg.Payload)}:
+				case <-subCtx.Done():
+					pubsub.Close()
+					return
+				}
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// RedisLeaseStore implements LeaseStore on a RedisCache. Its keys are prefixed with NamespaceKey like
+// the other locks of the cache, so they are shared between schema versions and never seen by a RedisStore.
+type RedisLeaseStore struct {
+	r *RedisCache
+}
+
+var _ LeaseStore = (*RedisLeaseStore)(nil)
+
+// NewRedisLeaseStore wraps r into a LeaseStore.
+func NewRedisLeaseStore(r *RedisCache) *RedisLeaseStore {
+	return &RedisLeaseStore{r: r}
+}
+
+func (s *RedisLeaseStore) SetNX(ctx context.Context, key string, value any, expiration time.Duration) (bool, error) {
+	return s.r.setLease(ctx, key, value, expiration)
+}
+
+func (s *RedisLeaseStore) CompareAndDelete(ctx context.Context, key string, value any) (bool, error) {
+	return s.r.compareAndDelete(ctx, s.r.NamespaceKey(key), value)
+}
+
+func (s *RedisLeaseStore) CompareAndExpire(ctx context.Context, key string, value any, expiration time.Duration) (bool, error) {
+	return s.r.compareAndExpire(ctx, s.r.NamespaceKey(key), value, expiration)
+}
+
+// compareAndDelete removes the Redis key only when it holds the encoded value.
+func (r *RedisCache) compareAndDelete(ctx context.Context, key string, value any) (bool, error) {
+	data, err := r.encode(value)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	n, err := releaseLockScript.Run(ctx, r.Universal, []string{key}, data).Int()
+	return n > 0, err
+}
+
+// compareAndExpire sets the time to live of the Redis key only when it holds the encoded value.
+func (r *RedisCache) compareAndExpire(ctx context.Context, key string, value any, expiration time.Duration) (bool, error) {
+	data, err := r.encode(value)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	n, err := extendLockScript.Run(ctx, r.Universal, []string{key}, data, expiration.Milliseconds()).Int()
+	return n > 0, err
+}
+
+type redisSubscription struct {
+	pubsub *redis.PubSub
+	ch     chan *Message
+}
+
+func (s *redisSubscription) Channel() <-chan *Message {
+	return s.ch
+}
+
+func (s *redisSubscription) Close() error {
+	return s.pubsub.Close()
+}
@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedisStore returns a RedisStore on a fresh miniredis, with a namespace and schema version
+// so that every key scheme of the store is exercised.
+func newTestRedisStore(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	t.Helper()
+	m := miniredis.RunT(t)
+	r, err := New(Config{Addr: m.Addr(), Namespace: "test", SchemaVersion: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		r.Close(&wg)
+	})
+	return NewRedisStore(r), m
+}
+
+// TestStores runs the same scenarios against RedisStore and MemoryStore.
+func TestStores(t *testing.T) {
+	stores := map[string]func(t *testing.T) Store{
+		"redis": func(t *testing.T) Store {
+			s, _ := newTestRedisStore(t)
+			return s
+		},
+		"memory": func(t *testing.T) Store {
+			return NewMemoryStore()
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			t.Run("SetNX shares keys with Get and Delete", func(t *testing.T) {
+				ctx := context.Background()
+				s := newStore(t)
+
+				ok, err := s.SetNX(ctx, "k", "a", time.Minute)
+				if err != nil || !ok {
+					t.Fatalf("SetNX = %v, %v, want true", ok, err)
+				}
+				if ok, err := s.SetNX(ctx, "k", "b", time.Minute); err != nil || ok {
+					t.Fatalf("second SetNX = %v, %v, want false", ok, err)
+				}
+
+				var got string
+				if err := s.Get(ctx, "k", &got); err != nil || got != "a" {
+					t.Fatalf("Get = %q, %v, want a", got, err)
+				}
+				if ok, err := s.Exists(ctx, "k"); err != nil || !ok {
+					t.Fatalf("Exists = %v, %v, want true", ok, err)
+				}
+				if n, err := s.Delete(ctx, "k"); err != nil || n != 1 {
+					t.Fatalf("Delete = %d, %v, want 1", n, err)
+				}
+				if err := s.Get(ctx, "k", &got); !errors.Is(err, redis.Nil) {
+					t.Fatalf("Get after Delete = %v, want redis.Nil", err)
+				}
+			})
+
+			t.Run("compare and swap", func(t *testing.T) {
+				ctx := context.Background()
+				s := newStore(t)
+
+				if err := s.Set(ctx, "k", "a", 0); err != nil {
+					t.Fatal(err)
+				}
+				if ok, err := s.CompareAndExpire(ctx, "k", "b", time.Minute); err != nil || ok {
+					t.Fatalf("CompareAndExpire with another value = %v, %v, want false", ok, err)
+				}
+				if ok, err := s.CompareAndExpire(ctx, "k", "a", time.Minute); err != nil || !ok {
+					t.Fatalf("CompareAndExpire = %v, %v, want true", ok, err)
+				}
+				if ok, err := s.CompareAndDelete(ctx, "k", "b"); err != nil || ok {
+					t.Fatalf("CompareAndDelete with another value = %v, %v, want false", ok, err)
+				}
+				if ok, err := s.CompareAndDelete(ctx, "k", "a"); err != nil || !ok {
+					t.Fatalf("CompareAndDelete = %v, %v, want true", ok, err)
+				}
+				if ok, err := s.Exists(ctx, "k"); err != nil || ok {
+					t.Fatalf("Exists after CompareAndDelete = %v, %v, want false", ok, err)
+				}
+				if ok, err := s.CompareAndDelete(ctx, "k", "a"); err != nil || ok {
+					t.Fatalf("CompareAndDelete of a missing key = %v, %v, want false", ok, err)
+				}
+			})
+
+			t.Run("leases", func(t *testing.T) {
+				ctx := context.Background()
+				leases := newStore(t).Leases()
+
+				if ok, err := leases.SetNX(ctx, "lock", "owner", time.Minute); err != nil || !ok {
+					t.Fatalf("SetNX = %v, %v, want true", ok, err)
+				}
+				if ok, err := leases.SetNX(ctx, "lock", "other", time.Minute); err != nil || ok {
+					t.Fatalf("second SetNX = %v, %v, want false", ok, err)
+				}
+				if ok, err := leases.CompareAndExpire(ctx, "lock", "owner", time.Minute); err != nil || !ok {
+					t.Fatalf("CompareAndExpire = %v, %v, want true", ok, err)
+				}
+				if ok, err := leases.CompareAndDelete(ctx, "lock", "other"); err != nil || ok {
+					t.Fatalf("CompareAndDelete by another owner = %v, %v, want false", ok, err)
+				}
+				if ok, err := leases.CompareAndDelete(ctx, "lock", "owner"); err != nil || !ok {
+					t.Fatalf("CompareAndDelete = %v, %v, want true", ok, err)
+				}
+			})
+
+			t.Run("Scan", func(t *testing.T) {
+				ctx := context.Background()
+				s := newStore(t)
+
+				for _, key := range []string{"user:1", "user:2", "order:1"} {
+					if err := s.Set(ctx, key, 1, 0); err != nil {
+						t.Fatal(err)
+					}
+				}
+				found := map[string]bool{}
+				err := s.Scan(ctx, "user:*", func(key string) error {
+					found[key] = true
+					return nil
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(found) != 2 || !found["user:1"] || !found["user:2"] {
+					t.Fatalf("Scan found %v, want user:1 and user:2", found)
+				}
+			})
+		})
+	}
+}
+
+func TestRedisLeaseStoreIgnoresSchemaVersion(t *testing.T) {
+	ctx := context.Background()
+	s, m := newTestRedisStore(t)
+
+	if ok, err := s.Leases().SetNX(ctx, "lock", "owner", time.Minute); err != nil || !ok {
+		t.Fatalf("SetNX = %v, %v, want true", ok, err)
+	}
+	if !m.Exists("test:lock") {
+		t.Fatalf("lease stored under %v, want test:lock", m.Keys())
+	}
+	if ok, err := s.Exists(ctx, "lock"); err != nil || ok {
+		t.Fatalf("RedisStore.Exists of a lease = %v, %v, want false", ok, err)
+	}
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// structure holds the key and TTL operations shared by the typed Redis data structure handles.
+// Elements written with another version of the element type are treated as missing, see SetTypeVersion.
+type structure struct {
+	r   *RedisCache
+	key string
+	// evict removes the raw elements written with an older version of the element type.
+	evict func(ctx context.Context, stale []any) error
+}
+
+// Key returns the Redis key the handle is bound to.
+func (s structure) Key() string {
+	return s.key
+}
+
+// Expire sets the time to live of the whole structure. It returns false when the key does not exist.
+func (s structure) Expire(ctx context.Context, expiration time.Duration) (bool, error) {
+	ctx, cancel := s.r.writeContext(ctx)
+	defer cancel()
+	return s.r.Universal.Expire(ctx, s.key, expiration).Result()
+}
+
+// TTL returns the remaining time to live of the structure, -1 when it has no expiry and -2 when it does not exist.
+func (s structure) TTL(ctx context.Context) (time.Duration, error) {
+	ctx, cancel := s.r.readContext(ctx)
+	defer cancel()
+	return s.r.Universal.PTTL(ctx, s.key).Result()
+}
+
+// Clear deletes the whole structure.
+func (s structure) Clear(ctx context.Context) error {
+	ctx, cancel := s.r.writeContext(ctx)
+	defer cancel()
+	return s.r.Universal.Del(ctx, s.key).Err()
+}
+
+func encodeAll[T any](r *RedisCache, values []T) ([]any, error) {
+	encoded := make([]any, len(values))
+	for i, v := range values {
+		data, err := r.encode(v)
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = data
+	}
+	return encoded, nil
+}
+
+// decodeAll decodes elements, skipping and evicting those written with another version of T.
+func decodeAll[T any](ctx context.Context, s structure, data []string) ([]T, error) {
+	values := make([]T, 0, len(data))
+	var stale []any
+	for _, d := range data {
+		var value T
+		err := s.r.decode([]byte(d), &value)
+		if older, ok := isStale(err); ok {
+			if older {
+				stale = append(stale, d)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	if len(stale) > 0 && s.evict != nil {
+		// Eviction is best effort, stale elements are skipped anyway.
+		s.evict(context.WithoutCancel(ctx), stale)
+	}
+	return values, nil
+}
+
+// decodeOne decodes a single element, turning one written with another version of T into redis.Nil.
+func decodeOne[T any](ctx context.Context, s structure, data string, err error) (T, error) {
+	var value T
+	if err != nil {
+		return value, err
+	}
+
+	err = s.r.decode([]byte(data), &value)
+	if older, ok := isStale(err); ok {
+		if older && s.evict != nil {
+			s.evict(context.WithoutCancel(ctx), []any{data})
+		}
+		return value, redis.Nil
+	}
+	return value, err
+}
+
+// isStale reports whether err is a stale version error, and whether the value was older.
+func isStale(err error) (older, ok bool) {
+	var stale *staleVersionError
+	if errors.As(err, &stale) {
+		return stale.older, true
+	}
+	return false, false
+}
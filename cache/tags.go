@@ -0,0 +1,282 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Tag membership is tracked in one set per tag holding the tagged keys, plus one set per key
+// holding its tags so that re-tagging a key removes it from the tags it no longer has.
+// The sets of a key live under the reserved "tagged:" prefix and the tag sets under "tag:".
+// SetWithTags touches several keys at once; in cluster mode the key and its tags must hash to the
+// same slot, for example by sharing a {hash tag}. Invalidation and pruning have no such requirement.
+
+// setWithTagsScript: KEYS = key, key tags set, ARGV[4] new tag sets, previous tag sets;
+// ARGV = value, ttl in ms (0 for none), key, number of new tag sets.
+// It returns 0 without writing when the tags of key are no longer the previous ones.
+var setWithTagsScript = redis.NewScript(`
+local n = tonumber(ARGV[4])
+local wanted = {}
+for i = 3, 2 + n do
+	wanted[KEYS[i]] = true
+end
+local previous = {}
+for i = 3 + n, #KEYS do
+	previous[KEYS[i]] = true
+end
+local current = redis.call("SMEMBERS", KEYS[2])
+if #current ~= #KEYS - 2 - n then
+	return 0
+end
+for _, old in ipairs(current) do
+	if not previous[old] then
+		return 0
+	end
+end
+
+local ttl = tonumber(ARGV[2])
+if ttl > 0 then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ttl)
+else
+	redis.call("SET", KEYS[1], ARGV[1])
+end
+
+for i = 3 + n, #KEYS do
+	if not wanted[KEYS[i]] then
+		redis.call("SREM", KEYS[i], ARGV[3])
+	end
+end
+redis.call("DEL", KEYS[2])
+
+for i = 3, 2 + n do
+	local existed = redis.call("EXISTS", KEYS[i])
+	redis.call("SADD", KEYS[i], ARGV[3])
+	redis.call("SADD", KEYS[2], KEYS[i])
+	-- A tag set lives as long as its longest lived member.
+	if ttl > 0 then
+		local current = redis.call("PTTL", KEYS[i])
+		if existed == 0 or (current >= 0 and current < ttl) then
+			redis.call("PEXPIRE", KEYS[i], ttl)
+		end
+	else
+		redis.call("PERSIST", KEYS[i])
+	end
+end
+if ttl > 0 and n > 0 then
+	redis.call("PEXPIRE", KEYS[2], ttl)
+end
+return 1
+`)
+
+// invalidateTagScript: KEYS = tag set. It deletes the tag set and returns its members, so that a key
+// tagged concurrently is either returned here or recorded in a new tag set, never dropped in between.
+var invalidateTagScript = redis.NewScript(`
+local members = redis.call("SMEMBERS", KEYS[1])
+redis.call("DEL", KEYS[1])
+return members
+`)
+
+// maxTagAttempts bounds how many times SetWithTags retries when the tags of a key change concurrently.
+const maxTagAttempts = 10
+
+// SetWithTags stores value under key and records it under every tag, replacing the previous tags of key.
+// It returns redis.TxFailedErr when the tags of key keep changing concurrently.
+func SetWithTags[T any](ctx context.Context, r *RedisCache, key string, value T, expiration time.Duration, tags ...string) error {
+	// The script works in milliseconds, a shorter expiration would store the value without one.
+	if expiration > 0 && expiration < time.Millisecond {
+		return errors.New("cache: expiration must be at least 1ms")
+	}
+	data, err := r.encode(value)
+	if err != nil {
+		return err
+	}
+
+	key = r.Key(key)
+	tagsKey := r.keyTagsKey(key)
+	tagKeys := r.tagKeys(tags)
+	ttl := r.jitter(expiration).Milliseconds()
+
+	writeCtx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	for attempt := 0; ; attempt++ {
+		if attempt == maxTagAttempts {
+			return redis.TxFailedErr
+		}
+		// The previous tags are passed to the script so that it only touches the keys it declares.
+		previous, err := r.Universal.SMembers(writeCtx, tagsKey).Result()
+		if err != nil {
+			return err
+		}
+		keys := make([]string, 0, 2+len(tagKeys)+len(previous))
+		keys = append(keys, key, tagsKey)
+		keys = append(keys, tagKeys...)
+		keys = append(keys, previous...)
+
+		written, err := setWithTagsScript.Run(writeCtx, r.Universal, keys, data, ttl, key, len(tagKeys)).Int()
+		if err != nil {
+			return err
+		}
+		if written == 1 {
+			break
+		}
+		// The tags of key changed since they were read, read them again.
+	}
+	r.invalidateNear(ctx, key)
+	return nil
+}
+
+// InvalidateTags deletes every key recorded under any of tags and returns how many keys were deleted.
+func InvalidateTags(ctx context.Context, r *RedisCache, tags ...string) (int, error) {
+	if len(tags) == 0 {
+		return 0, nil
+	}
+
+	writeCtx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	// Each tag set is read and deleted atomically, its keys are in other slots in cluster mode so they
+	// are removed afterwards with single key commands.
+	members := make(map[string][]string)
+	for _, tagKey := range r.tagKeys(tags) {
+		keys, err := invalidateTagScript.Run(writeCtx, r.Universal, []string{tagKey}).StringSlice()
+		if err != nil {
+			return 0, err
+		}
+		for _, key := range keys {
+			members[key] = append(members[key], tagKey)
+		}
+	}
+	if len(members) == 0 {
+		return 0, nil
+	}
+
+	keys := make([]string, 0, len(members))
+	delCmds := make([]*redis.IntCmd, 0, len(members))
+	_, err := r.Universal.Pipelined(writeCtx, func(pipe redis.Pipeliner) error {
+		for key, tagKeys := range members {
+			keys = append(keys, key)
+			delCmds = append(delCmds, pipe.Del(writeCtx, key))
+			// Only the invalidated tags are forgotten, a concurrent write may have recorded others.
+			pipe.SRem(writeCtx, r.keyTagsKey(key), toAny(tagKeys)...)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var deleted []string
+	for i, cmd := range delCmds {
+		if cmd.Val() == 1 {
+			deleted = append(deleted, keys[i])
+		}
+	}
+	if len(deleted) > 0 {
+		r.invalidateNear(ctx, deleted...)
+	}
+	return len(deleted), nil
+}
+
+// PruneTags removes keys that already expired from the membership of tags and returns how many were removed.
+// Expired members are harmless but accumulate in tags that are rarely invalidated.
+func PruneTags(ctx context.Context, r *RedisCache, tags ...string) (int, error) {
+	if len(tags) == 0 {
+		return 0, nil
+	}
+
+	writeCtx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	members, err := r.tagMembers(writeCtx, r.tagKeys(tags))
+	if err != nil {
+		return 0, err
+	}
+
+	keys := make([]string, 0, len(members))
+	existsCmds := make([]*redis.IntCmd, 0, len(members))
+	_, err = r.Universal.Pipelined(writeCtx, func(pipe redis.Pipeliner) error {
+		for key := range members {
+			keys = append(keys, key)
+			existsCmds = append(existsCmds, pipe.Exists(writeCtx, key))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var sremCmds []*redis.IntCmd
+	_, err = r.Universal.Pipelined(writeCtx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			if existsCmds[i].Val() > 0 {
+				continue
+			}
+			for _, tagKey := range members[key] {
+				sremCmds = append(sremCmds, pipe.SRem(writeCtx, tagKey, key))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, cmd := range sremCmds {
+		removed += int(cmd.Val())
+	}
+	return removed, nil
+}
+
+// tagMembers returns the keys recorded under any of the tag sets, with the tag sets recording each.
+func (r *RedisCache) tagMembers(ctx context.Context, tagKeys []string) (map[string][]string, error) {
+	cmds := make([]*redis.StringSliceCmd, len(tagKeys))
+	_, err := r.Universal.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, tagKey := range tagKeys {
+			cmds[i] = pipe.SMembers(ctx, tagKey)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[string][]string)
+	for i, cmd := range cmds {
+		for _, key := range cmd.Val() {
+			members[key] = append(members[key], tagKeys[i])
+		}
+	}
+	return members, nil
+}
+
+func (r *RedisCache) tagKeys(tags []string) []string {
+	keys := make([]string, len(tags))
+	for i, tag := range tags {
+		keys[i] = r.Key(tagKey(tag))
+	}
+	return keys
+}
+
+// keyTagsKey returns the set holding the tags of the Redis key of a cached value.
+func (r *RedisCache) keyTagsKey(key string) string {
+	prefix := r.Key("")
+	return prefix + "tagged:" + strings.TrimPrefix(key, prefix)
+}
+
+func toAny(values []string) []any {
+	args := make([]any, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
+
+func tagKey(tag string) string {
+	return "tag:" + tag
+}
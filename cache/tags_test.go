@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestRedisCache returns a RedisCache configured with cfg on a fresh miniredis.
+func newTestRedisCache(t *testing.T, cfg Config) (*RedisCache, *miniredis.Miniredis) {
+	t.Helper()
+	m := miniredis.RunT(t)
+	cfg.Addr = m.Addr()
+	r, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		r.Close(&wg)
+	})
+	return r, m
+}
+
+func TestInvalidateTags(t *testing.T) {
+	ctx := context.Background()
+	r, m := newTestRedisCache(t, Config{Namespace: "test"})
+
+	for _, set := range []struct {
+		key  string
+		tags []string
+	}{
+		{"menu:1", []string{"user:1", "menus"}},
+		{"profile:1", []string{"user:1"}},
+		{"menu:2", []string{"menus"}},
+	} {
+		if err := SetWithTags(ctx, r, set.key, "v", time.Minute, set.tags...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := InvalidateTags(ctx, r, "user:1")
+	if err != nil || n != 2 {
+		t.Fatalf("InvalidateTags = %d, %v, want 2", n, err)
+	}
+	for key, want := range map[string]bool{"menu:1": false, "profile:1": false, "menu:2": true} {
+		if ok, _ := ExistsContext(ctx, r, key); ok != want {
+			t.Errorf("Exists(%s) = %v, want %v", key, ok, want)
+		}
+	}
+	if m.Exists("test:tag:user:1") {
+		t.Error("the invalidated tag set was kept")
+	}
+	if tags, _ := m.Members("test:tagged:menu:1"); len(tags) != 1 || tags[0] != "test:tag:menus" {
+		t.Errorf("tags of menu:1 = %v, want only the tags that were not invalidated", tags)
+	}
+
+	// Writing the key again with new tags tracks it under them only.
+	if err := SetWithTags(ctx, r, "menu:1", "v", time.Minute, "user:2"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := InvalidateTags(ctx, r, "user:2"); err != nil || n != 1 {
+		t.Fatalf("InvalidateTags = %d, %v, want 1", n, err)
+	}
+}
+
+func TestSetWithTagsRejectsSubMillisecondExpiration(t *testing.T) {
+	r, m := newTestRedisCache(t, Config{Namespace: "test"})
+
+	if err := SetWithTags(context.Background(), r, "k", "v", time.Microsecond, "t"); err == nil {
+		t.Fatal("SetWithTags accepted a sub-millisecond expiration")
+	}
+	if m.Exists("test:k") {
+		t.Fatal("the value was written")
+	}
+}
@@ -1,87 +1,285 @@
 package cronjob
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 	"github.com/robfig/cron/v3"
 	"github.com/thanvuc/go-core-lib/cache"
+	"github.com/thanvuc/go-core-lib/log"
 )
 
 // CronScheduler defines the interface for a single scheduler
 // Only supports job > 2 minutes interval
 type CronScheduler interface {
-	ScheduleCronJob(schedule string, jobFunc func()) error
+	// ScheduleCronJob schedules jobFunc under jobName, which must be unique within the scheduler.
+	// Every job has its own lock: different jobs run concurrently, each on a single replica at a time.
+	ScheduleCronJob(jobName, schedule string, jobFunc func()) error
+	// ScheduleJob is ScheduleCronJob for jobs that take a context and report errors.
+	// The context is cancelled on Stop, when the job loses its lock or after WithTimeout.
+	// Errors are logged and recorded, see LastRun.
+	ScheduleJob(jobName, schedule string, job func(ctx context.Context) error, opts ...JobOption) error
+	// LastRun returns the outcome of the last run of jobName on any replica, or ErrNoRun.
+	LastRun(ctx context.Context, jobName string) (JobRun, error)
 	Start()
 	Stop()
 }
 
+// ErrNoRun is returned by LastRun when the job did not run yet.
+var ErrNoRun = errors.New("cronjob: job did not run yet")
+
+var (
+	errStopped   = errors.New("cronjob: scheduler stopped")
+	errLeaseLost = errors.New("cronjob: job lost its lock")
+)
+
+// JobRun is the outcome of a run of a job.
+type JobRun struct {
+	StartedAt time.Time
+	Duration  time.Duration
+	// Error is the error returned by the job, empty when it succeeded.
+	Error string
+}
+
+type JobOption func(*jobOptions)
+
+type jobOptions struct {
+	timeout time.Duration
+}
+
+// WithTimeout cancels the context of every run of the job after timeout.
+func WithTimeout(timeout time.Duration) JobOption {
+	return func(o *jobOptions) {
+		o.timeout = timeout
+	}
+}
+
+// leaseTTL is how long a job lock lives without a heartbeat, so that the lock of a replica
+// killed mid-job expires. holdAfterRun keeps the lock after a run so that replicas with
+// skewed clocks do not run the same tick again.
+const (
+	leaseTTL     = time.Minute
+	holdAfterRun = 2 * time.Minute
+)
+
 // RobfigCron implements CronScheduler
 type cronScheduler struct {
-	cron        *cron.Cron
-	logger      cron.Logger
-	redisClient *cache.RedisCache
-	lockKey     string
+	cron     *cron.Cron
+	logger   cron.Logger
+	store    cache.Store
+	leases   cache.LeaseStore
+	cronName string
+	// owner identifies this instance in the locks it holds.
+	owner string
+	// ctx is cancelled by Stop.
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	mu       sync.Mutex
+	lockKeys map[string]string
 }
 
+// NewCronScheduler creates a scheduler whose jobs run on a single replica at a time, using store for the lock.
+// Pass cache.NewRedisStore in production and cache.NewMemoryStore in tests.
+// The locks are held in store.Leases() and the last runs recorded in store.
+// The scheduler and cron log through logger, or cron.DefaultLogger when it is nil.
 func NewCronScheduler(
-	redisClient *cache.RedisCache,
+	store cache.Store,
 	cronName string,
+	logger log.Logger,
 	cronOpts ...cron.Option,
 ) CronScheduler {
-	lockKey := "cronjob-lock-" + cronName
+	cronLogger := cron.DefaultLogger
+	if logger != nil {
+		cronLogger = NewCronLogger(logger)
+		cronOpts = append([]cron.Option{cron.WithLogger(cronLogger)}, cronOpts...)
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
 	return &cronScheduler{
-		lockKey:     lockKey,
-		redisClient: redisClient,
-		cron:        cron.New(cronOpts...),
-		logger:      cron.DefaultLogger,
+		cronName: cronName,
+		owner:    uuid.NewString(),
+		ctx:      ctx,
+		cancel:   cancel,
+		lockKeys: make(map[string]string),
+		store:    store,
+		leases:   store.Leases(),
+		cron:     cron.New(cronOpts...),
+		logger:   cronLogger,
 	}
 }
 
 // ScheduleCronJob schedules a cron job with distributed locking
-func (r *cronScheduler) ScheduleCronJob(schedule string, jobFunc func()) error {
+func (r *cronScheduler) ScheduleCronJob(jobName, schedule string, jobFunc func()) error {
+	return r.ScheduleJob(jobName, schedule, func(context.Context) error {
+		jobFunc()
+		return nil
+	})
+}
+
+func (r *cronScheduler) ScheduleJob(jobName, schedule string, job func(ctx context.Context) error, opts ...JobOption) error {
 	if schedule == "" {
 		return nil
 	}
+	if jobName == "" {
+		return errors.New("cronjob: job name is required")
+	}
+
+	o := jobOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.lockKeys[jobName]; ok {
+		return fmt.Errorf("cronjob: job %q is already scheduled", jobName)
+	}
+	lockKey := "cronjob-lock-" + r.cronName + ":" + jobName
 
-	// Schedule the cron job
 	if _, err := r.cron.AddFunc(schedule, func() {
-		defer cache.RenewTTL(r.redisClient, r.lockKey, 2*time.Minute) // optional post-job TTL
-		// Recover from panic to ensure lock release
-		defer func(redisClient *cache.RedisCache, lockKey string, logger cron.Logger) {
-			if r := recover(); r != nil {
-				err := fmt.Errorf("%v", r) // convert recovered panic to error
-				logger.Error(err, "cron job panic recovered",
-					"lockKey", lockKey,
-				)
-				cache.Delete(redisClient, lockKey)
-			}
-		}(r.redisClient, r.lockKey, r.logger)
-
-		// Try to acquire lock atomically
-		ok, err := cache.SetNX(r.redisClient, r.lockKey, true, 0)
-		if err != nil {
+		r.run(jobName, lockKey, job, o)
+	}); err != nil {
+		return err
+	}
+
+	r.lockKeys[jobName] = lockKey
+	return nil
+}
+
+func (r *cronScheduler) LastRun(ctx context.Context, jobName string) (JobRun, error) {
+	var run JobRun
+	err := r.store.Get(ctx, r.statusKey(jobName), &run)
+	if errors.Is(err, redis.Nil) {
+		return run, ErrNoRun
+	}
+	return run, err
+}
+
+// run executes job while holding the lease on lockKey, renewing it until job returns.
+func (r *cronScheduler) run(jobName, lockKey string, job func(ctx context.Context) error, o jobOptions) {
+	if r.ctx.Err() != nil {
+		return
+	}
+	ctx := context.WithoutCancel(r.ctx)
+
+	// Try to acquire lock atomically
+	ok, err := r.leases.SetNX(ctx, lockKey, r.owner, leaseTTL)
+	if err != nil {
+		r.logger.Error(err, "cron job lock failed", "lockKey", lockKey)
+		return
+	}
+	if !ok {
+		// Another replica holds the lock, skip this run
+		return
+	}
+
+	jobCtx, cancel := context.WithCancelCause(r.ctx)
+	defer cancel(nil)
+	if o.timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		jobCtx, cancelTimeout = context.WithTimeout(jobCtx, o.timeout)
+		defer cancelTimeout()
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.heartbeat(ctx, lockKey, stop, cancel)
+	}()
+
+	start := time.Now()
+	err = r.call(jobCtx, job)
+	close(stop)
+	<-done
+
+	if err != nil {
+		if cause := context.Cause(jobCtx); cause != nil && cause != jobCtx.Err() && errors.Is(err, jobCtx.Err()) {
+			err = fmt.Errorf("%w: %w", err, cause)
+		}
+		r.logger.Error(err, "cron job failed", "job", jobName, "duration", time.Since(start))
+	}
+	r.record(ctx, jobName, start, err)
+
+	if err != nil {
+		// Let another replica retry on the next tick.
+		r.leases.CompareAndDelete(ctx, lockKey, r.owner)
+		return
+	}
+	r.leases.CompareAndExpire(ctx, lockKey, r.owner, holdAfterRun)
+}
+
+// call runs job, turning a panic into an error.
+func (r *cronScheduler) call(ctx context.Context, job func(ctx context.Context) error) (err error) {
+	// Recover from panic to ensure lock release
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("cron job panic: %v", p) // convert recovered panic to error
+		}
+	}()
+	return job(ctx)
+}
+
+// record stores the outcome of a run for LastRun.
+func (r *cronScheduler) record(ctx context.Context, jobName string, start time.Time, err error) {
+	run := JobRun{StartedAt: start, Duration: time.Since(start)}
+	if err != nil {
+		run.Error = err.Error()
+	}
+	if err := r.store.Set(ctx, r.statusKey(jobName), run, 0); err != nil {
+		r.logger.Error(err, "cron job status not recorded", "job", jobName)
+	}
+}
+
+func (r *cronScheduler) statusKey(jobName string) string {
+	return "cronjob-status-" + r.cronName + ":" + jobName
+}
+
+// heartbeat renews the lease on lockKey until stop is closed. It cancels the job when the lease is lost.
+func (r *cronScheduler) heartbeat(ctx context.Context, lockKey string, stop <-chan struct{}, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(leaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
 			return
+		case <-ticker.C:
+		}
+
+		ok, err := r.leases.CompareAndExpire(ctx, lockKey, r.owner, leaseTTL)
+		if err != nil {
+			// Retried on the next tick, the lease outlives a few failed renewals.
+			r.logger.Error(err, "cron job lease renewal failed", "lockKey", lockKey)
+			continue
 		}
 		if !ok {
-			// Lock already exists, skip scheduling
+			r.logger.Error(errLeaseLost, "cron job lost its lock while running", "lockKey", lockKey)
+			cancel(errLeaseLost)
 			return
 		}
-
-		jobFunc()
-	}); err != nil {
-		cache.Delete(r.redisClient, r.lockKey)
-		return err
 	}
-
-	return nil
 }
 
 func (r *cronScheduler) Start() {
 	r.cron.Start()
 }
 
+// Stop stops scheduling, cancels the context of the running jobs, waits for them to return and
+// releases the locks held by this instance.
 func (r *cronScheduler) Stop() {
-	r.cron.Stop()
-	cache.Delete(r.redisClient, r.lockKey)
+	r.cancel(errStopped)
+	<-r.cron.Stop().Done()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, lockKey := range r.lockKeys {
+		r.leases.CompareAndDelete(context.Background(), lockKey, r.owner)
+	}
 }
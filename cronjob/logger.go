@@ -0,0 +1,39 @@
+package cronjob
+
+import (
+	"fmt"
+
+	"github.com/robfig/cron/v3"
+	"github.com/thanvuc/go-core-lib/log"
+	"go.uber.org/zap"
+)
+
+type cronLogger struct {
+	logger log.Logger
+}
+
+// NewCronLogger adapts a log.Logger to the cron.Logger interface so it can be passed
+// to cron.WithLogger.
+func NewCronLogger(logger log.Logger) cron.Logger {
+	return &cronLogger{logger: logger}
+}
+
+func (l *cronLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.logger.Info(msg, "", toFields(keysAndValues)...)
+}
+
+func (l *cronLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.logger.Error(msg, "", append(toFields(keysAndValues), zap.Error(err))...)
+}
+
+// toFields converts cron's alternating key/value pairs into zap fields.
+func toFields(keysAndValues []interface{}) []zap.Field {
+	fields := make([]zap.Field, 0, len(keysAndValues)/2+1)
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		fields = append(fields, zap.Any(fmt.Sprint(keysAndValues[i]), keysAndValues[i+1]))
+	}
+	if len(keysAndValues)%2 == 1 {
+		fields = append(fields, zap.Any("extra", keysAndValues[len(keysAndValues)-1]))
+	}
+	return fields
+}
@@ -0,0 +1,310 @@
+package featureflag
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/thanvuc/go-core-lib/cache"
+	"github.com/thanvuc/go-core-lib/log"
+	"go.uber.org/zap"
+)
+
+// ChangeEvent is delivered to change listeners when the effective definition of a flag changes.
+// Flag is nil when the flag was removed.
+type ChangeEvent struct {
+	Key  string
+	Flag *Flag
+}
+
+// Client evaluates feature flags from an in-memory snapshot built from the configured
+// definitions merged with the overrides stored in Redis.
+// The snapshot is refreshed periodically and whenever an override is changed through any client.
+type Client struct {
+	redisCache *cache.RedisCache
+	cfg        Config
+	logger     log.Logger
+
+	mu        sync.RWMutex
+	flags     map[string]Flag
+	refreshMu sync.Mutex
+
+	listenersMu sync.Mutex
+	listeners   map[int]func(ChangeEvent)
+	nextID      int
+
+	cancel     context.CancelFunc
+	subscriber *cache.Subscriber
+	wg         sync.WaitGroup
+}
+
+// NewClient creates a feature flag client, loads the current overrides from Redis and starts
+// listening for changes. Call Close to stop the background refresh. A nil logger discards the logs.
+func NewClient(ctx context.Context, redisCache *cache.RedisCache, cfg Config, logger log.Logger) (*Client, error) {
+	withDefaults(&cfg)
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+
+	c := &Client{
+		redisCache: redisCache,
+		cfg:        cfg,
+		logger:     logger,
+		listeners:  make(map[int]func(ChangeEvent)),
+	}
+	// The definitions are copied so that compiling them does not modify the map of the caller.
+	c.cfg.Flags = make(map[string]Flag, len(cfg.Flags))
+	for key, flag := range cfg.Flags {
+		c.cfg.Flags[key] = c.compile(key, flag)
+	}
+	c.flags = maps.Clone(c.cfg.Flags)
+
+	if err := c.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	watchCtx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	changed := make(chan struct{}, 1)
+	subscriber, err := cache.Subscribe(watchCtx, redisCache, func(context.Context, cache.PubSubMessage[string]) {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}, c.channel())
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	c.subscriber = subscriber
+
+	c.wg.Add(1)
+	go c.watch(watchCtx, changed, subscriber.Done())
+
+	return c, nil
+}
+
+// Evaluate evaluates the flag for the given context.
+func (c *Client) Evaluate(key string, ec EvalContext) Evaluation {
+	c.mu.RLock()
+	flag, ok := c.flags[key]
+	c.mu.RUnlock()
+
+	if !ok {
+		return Evaluation{Flag: key, Reason: ReasonNotFound}
+	}
+	return evaluate(key, flag, ec)
+}
+
+// Bool returns the boolean value of the flag, or defaultValue when the flag does not exist
+// or its variant is not a boolean.
+func (c *Client) Bool(key string, ec EvalContext, defaultValue bool) bool {
+	if v, ok := c.Evaluate(key, ec).Value.(bool); ok {
+		return v
+	}
+	return defaultValue
+}
+
+// String returns the string value of the flag, or defaultValue when the flag does not exist
+// or its variant is not a string.
+func (c *Client) String(key string, ec EvalContext, defaultValue string) string {
+	if v, ok := c.Evaluate(key, ec).Value.(string); ok {
+		return v
+	}
+	return defaultValue
+}
+
+// Variant returns the name of the variant served to the context, or defaultVariant when
+// the flag does not exist.
+func (c *Client) Variant(key string, ec EvalContext, defaultVariant string) string {
+	eval := c.Evaluate(key, ec)
+	if eval.Reason == ReasonNotFound || eval.Variant == "" {
+		return defaultVariant
+	}
+	return eval.Variant
+}
+
+// Flags returns a copy of the effective flag definitions.
+func (c *Client) Flags() map[string]Flag {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return maps.Clone(c.flags)
+}
+
+// OnChange registers a listener that is called whenever the effective definition of a flag changes.
+// It returns a function that removes the listener.
+func (c *Client) OnChange(fn func(ChangeEvent)) func() {
+	c.listenersMu.Lock()
+	defer c.listenersMu.Unlock()
+
+	id := c.nextID
+	c.nextID++
+	c.listeners[id] = fn
+
+	return func() {
+		c.listenersMu.Lock()
+		defer c.listenersMu.Unlock()
+		delete(c.listeners, id)
+	}
+}
+
+// SetOverride stores a flag definition in Redis that replaces the configured definition
+// for all clients sharing the same key prefix.
+func (c *Client) SetOverride(ctx context.Context, key string, flag Flag) error {
+	data, err := json.Marshal(flag)
+	if err != nil {
+		return err
+	}
+	writeCtx, cancel := c.redisCache.WriteContext(ctx)
+	defer cancel()
+	if err := c.redisCache.Universal.HSet(writeCtx, c.overridesKey(), key, data).Err(); err != nil {
+		return err
+	}
+	return c.notify(ctx, key)
+}
+
+// DeleteOverride removes the override of a flag so the configured definition applies again.
+func (c *Client) DeleteOverride(ctx context.Context, key string) error {
+	writeCtx, cancel := c.redisCache.WriteContext(ctx)
+	defer cancel()
+	if err := c.redisCache.Universal.HDel(writeCtx, c.overridesKey(), key).Err(); err != nil {
+		return err
+	}
+	return c.notify(ctx, key)
+}
+
+// Refresh re-reads the overrides from Redis and notifies listeners about changed flags.
+func (c *Client) Refresh(ctx context.Context) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	readCtx, cancel := c.redisCache.ReadContext(ctx)
+	defer cancel()
+	raw, err := c.redisCache.Universal.HGetAll(readCtx, c.overridesKey()).Result()
+	if err != nil {
+		return err
+	}
+
+	next := maps.Clone(c.cfg.Flags)
+	for key, data := range raw {
+		var flag Flag
+		if err := json.Unmarshal([]byte(data), &flag); err != nil {
+			c.logger.Warn("Invalid feature flag override ignored", "",
+				zap.String("flag", key),
+				zap.Error(err),
+			)
+			continue
+		}
+		next[key] = c.compile(key, flag)
+	}
+
+	c.mu.Lock()
+	prev := c.flags
+	c.flags = next
+	c.mu.Unlock()
+
+	c.dispatch(diff(prev, next))
+	return nil
+}
+
+// compile compiles the patterns of flag, logging the invalid ones.
+func (c *Client) compile(key string, flag Flag) Flag {
+	flag, err := compileFlag(flag)
+	if err != nil {
+		c.logger.Warn("Invalid pattern in feature flag ignored", "",
+			zap.String("flag", key),
+			zap.Error(err),
+		)
+	}
+	return flag
+}
+
+// Close stops listening for changes.
+func (c *Client) Close() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+	if c.subscriber != nil {
+		c.subscriber.Close()
+	}
+	c.wg.Wait()
+}
+
+// watch refreshes the flags periodically and on changes until ctx is done or the subscription ended.
+func (c *Client) watch(ctx context.Context, changed, ended <-chan struct{}) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(c.cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ended:
+			return
+		case <-ticker.C:
+		case <-changed:
+		}
+
+		if err := c.Refresh(ctx); err != nil && ctx.Err() == nil {
+			c.logger.Error("Failed to refresh feature flags", "", zap.Error(err))
+		}
+	}
+}
+
+func (c *Client) notify(ctx context.Context, key string) error {
+	if _, err := cache.Publish(ctx, c.redisCache, c.channel(), key); err != nil {
+		return err
+	}
+	return c.Refresh(ctx)
+}
+
+func (c *Client) dispatch(events []ChangeEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	c.listenersMu.Lock()
+	listeners := make([]func(ChangeEvent), 0, len(c.listeners))
+	for _, fn := range c.listeners {
+		listeners = append(listeners, fn)
+	}
+	c.listenersMu.Unlock()
+
+	for _, event := range events {
+		for _, fn := range listeners {
+			fn(event)
+		}
+	}
+}
+
+func (c *Client) overridesKey() string {
+	return c.redisCache.NamespaceKey(c.cfg.KeyPrefix + ":overrides")
+}
+
+// channel is the pub/sub channel of override changes, namespaced by cache.Publish and cache.Subscribe.
+func (c *Client) channel() string {
+	return c.cfg.KeyPrefix + ":changes"
+}
+
+// diff returns a change event for every flag that was added, changed or removed.
+func diff(prev, next map[string]Flag) []ChangeEvent {
+	var events []ChangeEvent
+	for key, flag := range next {
+		if old, ok := prev[key]; !ok || !reflect.DeepEqual(old, flag) {
+			f := flag
+			events = append(events, ChangeEvent{Key: key, Flag: &f})
+		}
+	}
+	for key := range prev {
+		if _, ok := next[key]; !ok {
+			events = append(events, ChangeEvent{Key: key})
+		}
+	}
+	return events
+}
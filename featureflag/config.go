@@ -0,0 +1,82 @@
+package featureflag
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/thanvuc/go-core-lib/config"
+)
+
+type Config struct {
+	// Flags holds the flag definitions keyed by flag key.
+	Flags map[string]Flag
+	// KeyPrefix is prepended to the Redis keys and channel used for overrides.
+	KeyPrefix string
+	// RefreshInterval controls how often overrides are re-read from Redis in
+	// addition to change notifications.
+	RefreshInterval time.Duration
+}
+
+// Flag describes a boolean or multivariate feature flag.
+// A flag without Variants is a boolean flag with the variants "on" (true) and "off" (false).
+type Flag struct {
+	Description    string
+	Enabled        bool
+	Variants       map[string]any
+	DefaultVariant string
+	OffVariant     string
+	Rules          []Rule
+	Rollout        []WeightedVariant
+	Salt           string
+}
+
+// Rule serves a variant (or a rollout between variants) when all of its conditions match.
+type Rule struct {
+	Name       string
+	Conditions []Condition
+	Variant    string
+	Rollout    []WeightedVariant
+}
+
+// Condition matches a single attribute of the evaluation context.
+type Condition struct {
+	Attribute string
+	Operator  Operator
+	Values    []string
+
+	// patterns holds Values compiled by compileFlag for the matches operator, nil for invalid ones.
+	patterns []*regexp.Regexp
+}
+
+// WeightedVariant assigns a percentage (0-100) of the users to a variant.
+type WeightedVariant struct {
+	Variant string
+	Weight  float64
+}
+
+type fileConfig struct {
+	FeatureFlags Config
+}
+
+// LoadConfig reads the "featureFlags" section of the environment configuration file
+// located in path using config.LoadConfig.
+func LoadConfig(path string) (Config, error) {
+	var fc fileConfig
+	if err := config.LoadConfig(&fc, path); err != nil {
+		return Config{}, err
+	}
+	return fc.FeatureFlags, nil
+}
+
+// withDefaults fills in missing config fields with sane defaults.
+func withDefaults(cfg *Config) {
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = "featureflag"
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = 30 * time.Second
+	}
+	if cfg.Flags == nil {
+		cfg.Flags = make(map[string]Flag)
+	}
+}
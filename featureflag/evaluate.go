@@ -0,0 +1,237 @@
+package featureflag
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	VariantOn  = "on"
+	VariantOff = "off"
+)
+
+type Operator string
+
+const (
+	OperatorEquals      Operator = "equals"
+	OperatorNotEquals   Operator = "not_equals"
+	OperatorIn          Operator = "in"
+	OperatorNotIn       Operator = "not_in"
+	OperatorContains    Operator = "contains"
+	OperatorStartsWith  Operator = "starts_with"
+	OperatorEndsWith    Operator = "ends_with"
+	OperatorMatches     Operator = "matches"
+	OperatorGreaterThan Operator = "gt"
+	OperatorGreaterOrEq Operator = "gte"
+	OperatorLessThan    Operator = "lt"
+	OperatorLessOrEq    Operator = "lte"
+)
+
+type Reason string
+
+const (
+	ReasonNotFound    Reason = "not_found"
+	ReasonDisabled    Reason = "disabled"
+	ReasonTargetMatch Reason = "target_match"
+	ReasonRollout     Reason = "rollout"
+	ReasonDefault     Reason = "default"
+)
+
+// EvalContext carries the user and attributes a flag is evaluated for.
+// UserID is used for sticky percentage rollouts and is also matched as the "user_id" attribute.
+type EvalContext struct {
+	UserID     string
+	Attributes map[string]any
+}
+
+// Evaluation is the result of evaluating a flag.
+type Evaluation struct {
+	Flag    string
+	Variant string
+	Value   any
+	Reason  Reason
+	Rule    string
+}
+
+// evaluate resolves the variant served by flag for the given context.
+func evaluate(key string, flag Flag, ec EvalContext) Evaluation {
+	flag = normalize(flag)
+
+	if !flag.Enabled {
+		return result(key, flag, flag.OffVariant, ReasonDisabled, "")
+	}
+
+	for _, rule := range flag.Rules {
+		if !matchesAll(rule.Conditions, ec) {
+			continue
+		}
+		if len(rule.Rollout) > 0 {
+			if variant, ok := pickVariant(key, flag.Salt, rule.Rollout, ec.UserID); ok {
+				return result(key, flag, variant, ReasonTargetMatch, rule.Name)
+			}
+			continue
+		}
+		return result(key, flag, rule.Variant, ReasonTargetMatch, rule.Name)
+	}
+
+	if len(flag.Rollout) > 0 {
+		if variant, ok := pickVariant(key, flag.Salt, flag.Rollout, ec.UserID); ok {
+			return result(key, flag, variant, ReasonRollout, "")
+		}
+	}
+
+	return result(key, flag, flag.DefaultVariant, ReasonDefault, "")
+}
+
+// compileFlag returns a copy of flag with the patterns of its matches conditions compiled once,
+// rather than on every evaluation. Invalid patterns never match and are reported in the error.
+func compileFlag(flag Flag) (Flag, error) {
+	var errs []error
+	rules := slices.Clone(flag.Rules)
+	for i := range rules {
+		conditions := slices.Clone(rules[i].Conditions)
+		for j := range conditions {
+			c := &conditions[j]
+			if c.Operator != OperatorMatches {
+				continue
+			}
+			c.patterns = make([]*regexp.Regexp, len(c.Values))
+			for k, v := range c.Values {
+				re, err := regexp.Compile(v)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("featureflag: rule %q: %w", rules[i].Name, err))
+					continue
+				}
+				c.patterns[k] = re
+			}
+		}
+		rules[i].Conditions = conditions
+	}
+	flag.Rules = rules
+	return flag, errors.Join(errs...)
+}
+
+// normalize turns a flag without variants into a boolean flag and fills in default variants.
+func normalize(flag Flag) Flag {
+	if len(flag.Variants) == 0 {
+		flag.Variants = map[string]any{VariantOn: true, VariantOff: false}
+		if flag.DefaultVariant == "" {
+			flag.DefaultVariant = VariantOn
+		}
+		if flag.OffVariant == "" {
+			flag.OffVariant = VariantOff
+		}
+	}
+	if flag.OffVariant == "" {
+		flag.OffVariant = flag.DefaultVariant
+	}
+	return flag
+}
+
+func result(key string, flag Flag, variant string, reason Reason, rule string) Evaluation {
+	return Evaluation{
+		Flag:    key,
+		Variant: variant,
+		Value:   flag.Variants[variant],
+		Reason:  reason,
+		Rule:    rule,
+	}
+}
+
+// pickVariant places the user in a stable bucket between 0 and 100 and returns the
+// variant whose cumulative weight covers that bucket.
+// Rollouts require a user ID so that the same user always gets the same variant.
+func pickVariant(key, salt string, rollout []WeightedVariant, userID string) (string, bool) {
+	if userID == "" {
+		return "", false
+	}
+
+	bucket := bucketFor(key, salt, userID)
+	var cumulative float64
+	for _, wv := range rollout {
+		cumulative += wv.Weight
+		if bucket < cumulative {
+			return wv.Variant, true
+		}
+	}
+	return "", false
+}
+
+// bucketFor hashes the user into one of 10000 buckets and returns it as a percentage.
+func bucketFor(key, salt, userID string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(salt + ":" + key + ":" + userID))
+	return float64(h.Sum32()%10000) / 100
+}
+
+func matchesAll(conditions []Condition, ec EvalContext) bool {
+	for _, c := range conditions {
+		if !matches(c, ec) {
+			return false
+		}
+	}
+	return true
+}
+
+func matches(c Condition, ec EvalContext) bool {
+	raw, ok := attribute(c.Attribute, ec)
+	if !ok {
+		// Negative operators match when the attribute is absent.
+		return c.Operator == OperatorNotEquals || c.Operator == OperatorNotIn
+	}
+	value := fmt.Sprint(raw)
+
+	switch c.Operator {
+	case OperatorEquals, OperatorIn:
+		return slices.Contains(c.Values, value)
+	case OperatorNotEquals, OperatorNotIn:
+		return !slices.Contains(c.Values, value)
+	case OperatorContains:
+		return slices.ContainsFunc(c.Values, func(v string) bool { return strings.Contains(value, v) })
+	case OperatorStartsWith:
+		return slices.ContainsFunc(c.Values, func(v string) bool { return strings.HasPrefix(value, v) })
+	case OperatorEndsWith:
+		return slices.ContainsFunc(c.Values, func(v string) bool { return strings.HasSuffix(value, v) })
+	case OperatorMatches:
+		return slices.ContainsFunc(c.patterns, func(re *regexp.Regexp) bool {
+			return re != nil && re.MatchString(value)
+		})
+	case OperatorGreaterThan:
+		return compareNumbers(value, c.Values, func(a, b float64) bool { return a > b })
+	case OperatorGreaterOrEq:
+		return compareNumbers(value, c.Values, func(a, b float64) bool { return a >= b })
+	case OperatorLessThan:
+		return compareNumbers(value, c.Values, func(a, b float64) bool { return a < b })
+	case OperatorLessOrEq:
+		return compareNumbers(value, c.Values, func(a, b float64) bool { return a <= b })
+	default:
+		return false
+	}
+}
+
+func attribute(name string, ec EvalContext) (any, bool) {
+	if v, ok := ec.Attributes[name]; ok {
+		return v, true
+	}
+	if name == "user_id" && ec.UserID != "" {
+		return ec.UserID, true
+	}
+	return nil, false
+}
+
+func compareNumbers(value string, values []string, cmp func(a, b float64) bool) bool {
+	a, err := strconv.ParseFloat(value, 64)
+	if err != nil || len(values) == 0 {
+		return false
+	}
+	b, err := strconv.ParseFloat(values[0], 64)
+	if err != nil {
+		return false
+	}
+	return cmp(a, b)
+}
@@ -0,0 +1,224 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+)
+
+type MiddlewareOption func(*middlewareOptions)
+
+type middlewareOptions struct {
+	header     string
+	methods    []string
+	required   bool
+	scope      func(r *http.Request) string
+	maxBody    int64
+	failClosed bool
+	onError    func(r *http.Request, err error)
+}
+
+// WithHeader reads the key from another header than Idempotency-Key.
+func WithHeader(name string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.header = name
+	}
+}
+
+// WithMethods sets the methods handled by the middleware, POST and PATCH by default.
+func WithMethods(methods ...string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.methods = methods
+	}
+}
+
+// WithRequired rejects requests of the handled methods without a key with 400.
+func WithRequired() MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.required = true
+	}
+}
+
+// WithScope prefixes keys with the scope returned for the request, typically the user ID,
+// so that keys chosen by different clients do not collide.
+func WithScope(scope func(r *http.Request) string) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.scope = scope
+	}
+}
+
+// WithMaxBodySize rejects requests whose body is larger than n bytes with 413, 1 MiB by default.
+func WithMaxBodySize(n int64) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.maxBody = n
+	}
+}
+
+// WithFailClosed rejects requests with 503 when the store fails instead of letting them through.
+func WithFailClosed() MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.failClosed = true
+	}
+}
+
+// WithErrorHook is called when the store fails, for example to log the error.
+func WithErrorHook(fn func(r *http.Request, err error)) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.onError = fn
+	}
+}
+
+// Middleware makes requests carrying an Idempotency-Key header safe to retry.
+// The first request with a key runs and its response is recorded; retries with the same key and
+// body get the recorded response with the Idempotent-Replayed header, retries arriving while it
+// runs get 409 and reuses of the key for another request get 422.
+// Responses with a 5xx status are not recorded, so that the request can be retried.
+func Middleware(store *Store, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	o := middlewareOptions{
+		header:  "Idempotency-Key",
+		methods: []string{http.MethodPost, http.MethodPatch},
+		maxBody: 1 << 20,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !slices.Contains(o.methods, r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key := r.Header.Get(o.header)
+			if key == "" {
+				if o.required {
+					http.Error(w, "missing "+o.header+" header", http.StatusBadRequest)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if o.scope != nil {
+				key = o.scope(r) + ":" + key
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, o.maxBody))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+					return
+				}
+				http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			token, replay, err := store.Begin(r.Context(), key, fingerprint(r, body))
+			switch {
+			case errors.Is(err, ErrInProgress):
+				http.Error(w, "a request with this idempotency key is in progress", http.StatusConflict)
+				return
+			case errors.Is(err, ErrFingerprintMismatch):
+				http.Error(w, "idempotency key reused with a different request", http.StatusUnprocessableEntity)
+				return
+			case err != nil:
+				if o.onError != nil {
+					o.onError(r, err)
+				}
+				if o.failClosed {
+					http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			case replay != nil:
+				writeReplay(w, replay)
+				return
+			}
+
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			// The outcome is recorded even when the client went away, it may retry.
+			ctx := context.WithoutCancel(r.Context())
+			defer func() {
+				if p := recover(); p != nil {
+					store.Release(ctx, key, token)
+					panic(p)
+				}
+			}()
+			next.ServeHTTP(rec, r)
+			if !rec.wroteHeader {
+				rec.header = w.Header().Clone()
+			}
+
+			if rec.status >= http.StatusInternalServerError {
+				err = store.Release(ctx, key, token)
+			} else {
+				err = store.Complete(ctx, key, token, Response{
+					StatusCode: rec.status,
+					Header:     rec.header,
+					Body:       rec.body.Bytes(),
+				})
+			}
+			if err != nil && o.onError != nil {
+				o.onError(r, err)
+			}
+		})
+	}
+}
+
+// fingerprint identifies a request by its method, path and body.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func writeReplay(w http.ResponseWriter, resp *Response) {
+	h := w.Header()
+	for name, values := range resp.Header {
+		h[name] = values
+	}
+	h.Set("Idempotent-Replayed", "true")
+	w.WriteHeader(resp.StatusCode)
+	w.Write(resp.Body)
+}
+
+// recorder captures the response written by the handler while passing it through.
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	header      http.Header
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.status = status
+	rec.header = rec.ResponseWriter.Header().Clone()
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rec *recorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,36 @@
+package idempotency
+
+import "github.com/redis/go-redis/v9"
+
+// Records are hashes with the fields status, fingerprint, token (while in progress) and response (once completed).
+
+// beginScript: KEYS[1] = record, ARGV = fingerprint, token, in progress TTL in milliseconds.
+// It returns {status, fingerprint, response} of an existing record, or false after creating it.
+var beginScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return redis.call("HMGET", KEYS[1], "status", "fingerprint", "response")
+end
+redis.call("HSET", KEYS[1], "status", "in_progress", "fingerprint", ARGV[1], "token", ARGV[2])
+redis.call("PEXPIRE", KEYS[1], ARGV[3])
+return false
+`)
+
+// completeScript: KEYS[1] = record, ARGV = token, response, TTL in milliseconds.
+// It returns 1 when the record was still owned by token and is now completed.
+var completeScript = redis.NewScript(`
+if redis.call("HGET", KEYS[1], "token") ~= ARGV[1] then
+	return 0
+end
+redis.call("HSET", KEYS[1], "status", "completed", "response", ARGV[2])
+redis.call("HDEL", KEYS[1], "token")
+redis.call("PEXPIRE", KEYS[1], ARGV[3])
+return 1
+`)
+
+// releaseScript: KEYS[1] = record, ARGV[1] = token. It deletes the record when still owned by token.
+var releaseScript = redis.NewScript(`
+if redis.call("HGET", KEYS[1], "token") == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
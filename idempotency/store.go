@@ -0,0 +1,135 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/thanvuc/go-core-lib/cache"
+)
+
+// ErrInProgress is returned by Begin when a request with the same key is still being processed.
+var ErrInProgress = errors.New("idempotency: request in progress")
+
+// ErrFingerprintMismatch is returned by Begin when the key was used for a different request.
+var ErrFingerprintMismatch = errors.New("idempotency: key reused with a different request")
+
+// ErrNotOwner is returned by Complete when the record expired or was taken over before completion.
+var ErrNotOwner = errors.New("idempotency: record no longer owned")
+
+type Config struct {
+	// KeyPrefix is prepended to the Redis keys of the records.
+	KeyPrefix string
+	// TTL is how long completed responses are replayed.
+	TTL time.Duration
+	// InProgressTTL bounds how long a key stays locked by a request that never completes,
+	// for example because its replica crashed. It should exceed the longest request.
+	InProgressTTL time.Duration
+}
+
+// withDefaults fills in missing config fields with sane defaults.
+func withDefaults(cfg *Config) {
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = "idempotency"
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.InProgressTTL <= 0 {
+		cfg.InProgressTTL = time.Minute
+	}
+}
+
+// Response is a recorded response replayed for retries of the same request.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Store records the state of idempotency keys in Redis.
+type Store struct {
+	redisCache *cache.RedisCache
+	cfg        Config
+}
+
+func NewStore(redisCache *cache.RedisCache, cfg Config) *Store {
+	withDefaults(&cfg)
+	return &Store{redisCache: redisCache, cfg: cfg}
+}
+
+// Begin claims key for the request identified by fingerprint.
+// For a new key it records it in progress and returns a token to pass to Complete or Release.
+// For a key completed with the same fingerprint it returns the recorded response.
+// Otherwise it returns ErrInProgress or ErrFingerprintMismatch.
+func (s *Store) Begin(ctx context.Context, key, fingerprint string) (string, *Response, error) {
+	token := uuid.NewString()
+
+	ctx, cancel := s.redisCache.WriteContext(ctx)
+	defer cancel()
+
+	res, err := beginScript.Run(ctx, s.redisCache.Universal, []string{s.key(key)},
+		fingerprint, token, s.cfg.InProgressTTL.Milliseconds()).Slice()
+	if err == redis.Nil {
+		return token, nil, nil
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("idempotency: %w", err)
+	}
+
+	status, _ := res[0].(string)
+	recorded, _ := res[1].(string)
+	if recorded != fingerprint {
+		return "", nil, ErrFingerprintMismatch
+	}
+	if status != "completed" {
+		return "", nil, ErrInProgress
+	}
+
+	data, _ := res[2].(string)
+	var resp Response
+	if err := s.redisCache.Decode([]byte(data), &resp); err != nil {
+		return "", nil, fmt.Errorf("idempotency: decode response: %w", err)
+	}
+	return "", &resp, nil
+}
+
+// Complete records resp for the key claimed with token, to be replayed for TTL.
+func (s *Store) Complete(ctx context.Context, key, token string, resp Response) error {
+	data, err := s.redisCache.Encode(resp)
+	if err != nil {
+		return fmt.Errorf("idempotency: encode response: %w", err)
+	}
+
+	ctx, cancel := s.redisCache.WriteContext(ctx)
+	defer cancel()
+
+	ok, err := completeScript.Run(ctx, s.redisCache.Universal, []string{s.key(key)},
+		token, data, s.cfg.TTL.Milliseconds()).Bool()
+	if err != nil {
+		return fmt.Errorf("idempotency: %w", err)
+	}
+	if !ok {
+		return ErrNotOwner
+	}
+	return nil
+}
+
+// Release forgets the key claimed with token so that the request can be retried, for example after a failure.
+func (s *Store) Release(ctx context.Context, key, token string) error {
+	ctx, cancel := s.redisCache.WriteContext(ctx)
+	defer cancel()
+
+	if err := releaseScript.Run(ctx, s.redisCache.Universal, []string{s.key(key)}, token).Err(); err != nil {
+		return fmt.Errorf("idempotency: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) key(key string) string {
+	return s.redisCache.NamespaceKey(s.cfg.KeyPrefix + ":" + key)
+}
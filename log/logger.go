@@ -101,3 +101,20 @@ func parseLevel(level string) zapcore.Level {
 		return zapcore.InfoLevel
 	}
 }
+
+// NewNopLogger creates a Logger that discards everything, for components built without a logger.
+func NewNopLogger() Logger {
+	return nopLogger{}
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Info(message, requestID string, fields ...zap.Field)  {}
+func (nopLogger) Error(message, requestID string, fields ...zap.Field) {}
+func (nopLogger) Debug(message, requestID string, fields ...zap.Field) {}
+func (nopLogger) Warn(message, requestID string, fields ...zap.Field)  {}
+
+func (nopLogger) Sync(wg *sync.WaitGroup) error {
+	wg.Done()
+	return nil
+}
@@ -0,0 +1,52 @@
+package queue
+
+import "time"
+
+type Config struct {
+	// Group is the consumer group sharing the jobs of the queue.
+	Group string
+	// Concurrency is the number of jobs handled at the same time by a consumer.
+	Concurrency int
+	// VisibilityTimeout is how long a job may stay unacknowledged without a heartbeat before
+	// another consumer claims it, for example after the consumer handling it crashed.
+	// Consumers renew their jobs while the handler runs.
+	VisibilityTimeout time.Duration
+	// MaxAttempts is the number of times a job is tried before it is moved to the dead-letter stream.
+	MaxAttempts int
+	// RetryBackoff is the delay before the second attempt of a failed job, multiplied by the attempt number after that.
+	RetryBackoff time.Duration
+	// BlockTimeout is how long a read waits for new jobs.
+	BlockTimeout time.Duration
+	// ClaimInterval is how often abandoned jobs are looked for.
+	ClaimInterval time.Duration
+	// DelayPollInterval is how often delayed jobs that became due are moved to the stream.
+	DelayPollInterval time.Duration
+}
+
+// withDefaults fills in missing config fields with sane defaults.
+func withDefaults(cfg *Config) {
+	if cfg.Group == "" {
+		cfg.Group = "workers"
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.VisibilityTimeout <= 0 {
+		cfg.VisibilityTimeout = 30 * time.Second
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = time.Second
+	}
+	if cfg.BlockTimeout <= 0 {
+		cfg.BlockTimeout = 5 * time.Second
+	}
+	if cfg.ClaimInterval <= 0 {
+		cfg.ClaimInterval = cfg.VisibilityTimeout / 2
+	}
+	if cfg.DelayPollInterval <= 0 {
+		cfg.DelayPollInterval = time.Second
+	}
+}
@@ -0,0 +1,340 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// settleTimeout bounds the acknowledgement of a job once its handler returned, even during shutdown.
+const settleTimeout = 5 * time.Second
+
+type consumer[T any] struct {
+	q       *Queue[T]
+	handler Handler[T]
+	name    string
+	slots   chan struct{}
+	running sync.WaitGroup
+}
+
+// Consume handles the jobs of the queue with up to Concurrency handlers at a time until ctx is done.
+// It also claims the jobs abandoned by crashed consumers and moves delayed jobs to the queue when due.
+// It returns once the running handlers returned; jobs interrupted by the shutdown are retried later.
+func (q *Queue[T]) Consume(ctx context.Context, handler Handler[T]) error {
+	err := q.redisCache.Universal.XGroupCreateMkStream(ctx, q.stream, q.cfg.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("queue: create consumer group: %w", err)
+	}
+
+	host, _ := os.Hostname()
+	c := &consumer[T]{
+		q:       q,
+		handler: handler,
+		name:    host + "-" + uuid.NewString()[:8],
+		slots:   make(chan struct{}, q.cfg.Concurrency),
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		c.read(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		c.claim(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		c.moveDue(ctx)
+	}()
+	wg.Wait()
+	c.running.Wait()
+	return nil
+}
+
+// acquire waits for a free handler slot. It returns false when ctx is done.
+func (c *consumer[T]) acquire(ctx context.Context) bool {
+	select {
+	case c.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (c *consumer[T]) release() {
+	<-c.slots
+}
+
+// read delivers new jobs. A job is only read once a slot is free so that it does not wait unacknowledged.
+func (c *consumer[T]) read(ctx context.Context) {
+	q := c.q
+	for c.acquire(ctx) {
+		streams, err := q.redisCache.Universal.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    q.cfg.Group,
+			Consumer: c.name,
+			Streams:  []string{q.stream, ">"},
+			Count:    1,
+			Block:    q.cfg.BlockTimeout,
+		}).Result()
+		if err != nil || len(streams) == 0 || len(streams[0].Messages) == 0 {
+			c.release()
+			if err != nil && err != redis.Nil && ctx.Err() == nil {
+				q.logger.Warn("Failed to read jobs", "", zap.String("queue", q.name), zap.Error(err))
+				sleep(ctx, time.Second)
+			}
+			continue
+		}
+		if ctx.Err() != nil {
+			// Read while shutting down, the job stays pending and is claimed later.
+			c.release()
+			return
+		}
+		c.handle(ctx, streams[0].Messages[0], 1)
+	}
+}
+
+// claim takes over the jobs left unacknowledged for longer than the visibility timeout.
+func (c *consumer[T]) claim(ctx context.Context) {
+	q := c.q
+	ticker := time.NewTicker(q.cfg.ClaimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		start := "0-0"
+		for c.acquire(ctx) {
+			msgs, next, err := q.redisCache.Universal.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+				Stream:   q.stream,
+				Group:    q.cfg.Group,
+				Consumer: c.name,
+				MinIdle:  q.cfg.VisibilityTimeout,
+				Start:    start,
+				Count:    1,
+			}).Result()
+			if err != nil || len(msgs) == 0 {
+				c.release()
+				if err != nil && ctx.Err() == nil {
+					q.logger.Warn("Failed to claim abandoned jobs", "", zap.String("queue", q.name), zap.Error(err))
+				}
+				if err != nil || next == "0-0" {
+					break
+				}
+				start = next
+				continue
+			}
+
+			deliveries := int64(1)
+			pending, err := q.redisCache.Universal.XPendingExt(ctx, &redis.XPendingExtArgs{
+				Stream: q.stream,
+				Group:  q.cfg.Group,
+				Start:  msgs[0].ID,
+				End:    msgs[0].ID,
+				Count:  1,
+			}).Result()
+			if err == nil && len(pending) == 1 {
+				deliveries = pending[0].RetryCount
+			}
+			c.handle(ctx, msgs[0], deliveries)
+
+			if next == "0-0" {
+				break
+			}
+			start = next
+		}
+	}
+}
+
+// moveDue moves the delayed jobs that became due to the stream.
+func (c *consumer[T]) moveDue(ctx context.Context) {
+	q := c.q
+	ticker := time.NewTicker(q.cfg.DelayPollInterval)
+	defer ticker.Stop()
+
+	const batch = 100
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			now := time.Now().UnixMilli()
+			moved, err := moveDueScript.Run(ctx, q.redisCache.Universal, []string{q.delayed, q.stream}, now, batch).Int()
+			if err != nil {
+				if ctx.Err() == nil {
+					q.logger.Warn("Failed to move delayed jobs", "", zap.String("queue", q.name), zap.Error(err))
+				}
+				break
+			}
+			if moved < batch {
+				break
+			}
+		}
+	}
+}
+
+// handle runs the handler for msg in its own goroutine and releases the slot taken for it.
+// deliveries is the number of times msg was delivered by the stream, more than 1 for claimed jobs.
+func (c *consumer[T]) handle(ctx context.Context, msg redis.XMessage, deliveries int64) {
+	c.running.Add(1)
+	go func() {
+		defer c.running.Done()
+		defer c.release()
+
+		q := c.q
+		settleCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), settleTimeout)
+		defer cancel()
+
+		job, err := q.decodeJob(msg)
+		if err != nil {
+			q.logger.Error("Moving malformed job to the dead-letter stream", "", zap.String("queue", q.name), zap.Error(err))
+			c.bury(settleCtx, msg, 0, err)
+			return
+		}
+		// Deliveries beyond the first one were lost by crashed consumers.
+		job.Attempt += int(deliveries) - 1
+		if job.Attempt > q.cfg.MaxAttempts {
+			c.bury(settleCtx, msg, job.Attempt-1, errors.New("queue: abandoned by its consumer"))
+			return
+		}
+
+		jobCtx, cancelJob := context.WithCancel(ctx)
+		lost := c.heartbeat(jobCtx, cancelJob, msg.ID)
+		err = c.run(jobCtx, job)
+		cancelJob()
+
+		switch {
+		case lost.Load():
+			// Another consumer claimed the job, it owns the outcome now.
+			q.logger.Warn("Job claimed by another consumer while running", "",
+				zap.String("queue", q.name), zap.String("job_id", job.ID))
+		case err == nil:
+			c.ack(settleCtx, msg)
+		case ctx.Err() != nil:
+			// Interrupted by the shutdown, the job stays pending and is claimed later.
+		case job.Attempt >= q.cfg.MaxAttempts:
+			q.logger.Error("Job failed, moving it to the dead-letter stream", "",
+				zap.String("queue", q.name), zap.String("job_id", job.ID), zap.Int("attempt", job.Attempt), zap.Error(err))
+			c.bury(settleCtx, msg, job.Attempt, err)
+		default:
+			q.logger.Warn("Job failed, retrying", "",
+				zap.String("queue", q.name), zap.String("job_id", job.ID), zap.Int("attempt", job.Attempt), zap.Error(err))
+			c.retry(settleCtx, msg, job)
+		}
+	}()
+}
+
+// run calls the handler, turning a panic into an error.
+func (c *consumer[T]) run(ctx context.Context, job Job[T]) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("queue: handler panic: %v", r)
+		}
+	}()
+	return c.handler(ctx, job)
+}
+
+// heartbeat resets the idle time of the job while ctx is not done, so that it is not claimed by
+// another consumer. When the job was claimed anyway it reports it and cancels the handler.
+func (c *consumer[T]) heartbeat(ctx context.Context, cancel context.CancelFunc, id string) *atomic.Bool {
+	q := c.q
+	lost := &atomic.Bool{}
+	go func() {
+		ticker := time.NewTicker(q.cfg.VisibilityTimeout / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			// Only the owner touches the entry, a job claimed by another consumer is not taken back.
+			owned, err := touchScript.Run(ctx, q.redisCache.Universal, []string{q.stream}, q.cfg.Group, id, c.name).Int()
+			if err == nil && owned == 0 {
+				lost.Store(true)
+				cancel()
+				return
+			}
+		}
+	}()
+	return lost
+}
+
+func (c *consumer[T]) ack(ctx context.Context, msg redis.XMessage) {
+	q := c.q
+	_, err := q.redisCache.Universal.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAck(ctx, q.stream, q.cfg.Group, msg.ID)
+		pipe.XDel(ctx, q.stream, msg.ID)
+		return nil
+	})
+	if err != nil {
+		q.logger.Error("Failed to acknowledge job", "", zap.String("queue", q.name), zap.String("entry_id", msg.ID), zap.Error(err))
+	}
+}
+
+// retry replaces msg with a delayed copy for the next attempt.
+func (c *consumer[T]) retry(ctx context.Context, msg redis.XMessage, job Job[T]) {
+	q := c.q
+	payload, _ := msg.Values["payload"].(string)
+	at := time.Now().Add(q.cfg.RetryBackoff * time.Duration(job.Attempt))
+	_, err := q.redisCache.Universal.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAck(ctx, q.stream, q.cfg.Group, msg.ID)
+		pipe.XDel(ctx, q.stream, msg.ID)
+		pipe.ZAdd(ctx, q.delayed, delayedMember(job.ID, job.Attempt+1, at, []byte(payload)))
+		return nil
+	})
+	if err != nil {
+		q.logger.Error("Failed to schedule job retry", "", zap.String("queue", q.name), zap.String("job_id", job.ID), zap.Error(err))
+	}
+}
+
+// bury moves msg to the dead-letter stream. attempt is the number of attempts made, 0 when unknown.
+func (c *consumer[T]) bury(ctx context.Context, msg redis.XMessage, attempt int, cause error) {
+	q := c.q
+	values := []any{"error", cause.Error()}
+	for _, field := range []string{"id", "payload"} {
+		if v, ok := msg.Values[field]; ok {
+			values = append(values, field, v)
+		}
+	}
+	if attempt > 0 {
+		values = append(values, "attempt", strconv.Itoa(attempt))
+	} else if v, ok := msg.Values["attempt"]; ok {
+		values = append(values, "attempt", v)
+	}
+
+	_, err := q.redisCache.Universal.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(ctx, &redis.XAddArgs{Stream: q.dead, Values: values})
+		pipe.XAck(ctx, q.stream, q.cfg.Group, msg.ID)
+		pipe.XDel(ctx, q.stream, msg.ID)
+		return nil
+	})
+	if err != nil {
+		q.logger.Error("Failed to move job to the dead-letter stream", "", zap.String("queue", q.name), zap.String("entry_id", msg.ID), zap.Error(err))
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
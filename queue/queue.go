@@ -0,0 +1,208 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/thanvuc/go-core-lib/cache"
+	"github.com/thanvuc/go-core-lib/log"
+)
+
+// Job is a unit of work read from the queue.
+type Job[T any] struct {
+	// ID identifies the job across its attempts.
+	ID      string
+	Payload T
+	// Attempt is 1 for the first try and grows with every retry.
+	Attempt int
+}
+
+// DeadLetter is a job that failed MaxAttempts times.
+type DeadLetter[T any] struct {
+	Job[T]
+	// EntryID is the ID of the entry in the dead-letter stream, used by Requeue.
+	EntryID string
+	Error   string
+}
+
+// Handler processes a job. A nil error acknowledges it, any other error schedules a retry.
+type Handler[T any] func(ctx context.Context, job Job[T]) error
+
+// Queue is a work queue of T stored in a Redis stream and shared by a consumer group.
+// Jobs are delivered at least once: handlers must tolerate running twice for the same job.
+type Queue[T any] struct {
+	redisCache *cache.RedisCache
+	name       string
+	cfg        Config
+	logger     log.Logger
+
+	stream  string
+	dead    string
+	delayed string
+}
+
+// New creates the queue called name. Its keys share a hash tag so that they live in the same cluster slot.
+// A nil logger discards the logs of the consumers.
+func New[T any](redisCache *cache.RedisCache, name string, cfg Config, logger log.Logger) (*Queue[T], error) {
+	if name == "" {
+		return nil, errors.New("queue: name is required")
+	}
+	withDefaults(&cfg)
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+
+	prefix := redisCache.NamespaceKey("queue:{" + name + "}:")
+	return &Queue[T]{
+		redisCache: redisCache,
+		name:       name,
+		cfg:        cfg,
+		logger:     logger,
+		stream:     prefix + "stream",
+		dead:       prefix + "dead",
+		delayed:    prefix + "delayed",
+	}, nil
+}
+
+// Enqueue adds a job to the queue and returns its ID.
+func (q *Queue[T]) Enqueue(ctx context.Context, payload T) (string, error) {
+	data, err := q.redisCache.Encode(payload)
+	if err != nil {
+		return "", fmt.Errorf("queue: %w", err)
+	}
+
+	ctx, cancel := q.redisCache.WriteContext(ctx)
+	defer cancel()
+
+	id := uuid.NewString()
+	if err := q.redisCache.Universal.XAdd(ctx, q.entry(id, 1, data)).Err(); err != nil {
+		return "", fmt.Errorf("queue: %w", err)
+	}
+	return id, nil
+}
+
+// EnqueueAt adds a job that is not delivered before at and returns its ID.
+func (q *Queue[T]) EnqueueAt(ctx context.Context, payload T, at time.Time) (string, error) {
+	data, err := q.redisCache.Encode(payload)
+	if err != nil {
+		return "", fmt.Errorf("queue: %w", err)
+	}
+
+	ctx, cancel := q.redisCache.WriteContext(ctx)
+	defer cancel()
+
+	id := uuid.NewString()
+	if err := q.redisCache.Universal.ZAdd(ctx, q.delayed, delayedMember(id, 1, at, data)).Err(); err != nil {
+		return "", fmt.Errorf("queue: %w", err)
+	}
+	return id, nil
+}
+
+// EnqueueIn adds a job that is not delivered before delay elapsed and returns its ID.
+func (q *Queue[T]) EnqueueIn(ctx context.Context, payload T, delay time.Duration) (string, error) {
+	return q.EnqueueAt(ctx, payload, time.Now().Add(delay))
+}
+
+// Len returns the number of jobs in the stream, including those being handled, and the number of delayed jobs.
+func (q *Queue[T]) Len(ctx context.Context) (ready, delayed int64, err error) {
+	ctx, cancel := q.redisCache.ReadContext(ctx)
+	defer cancel()
+
+	var xlen *redis.IntCmd
+	var zcard *redis.IntCmd
+	_, err = q.redisCache.Universal.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		xlen = pipe.XLen(ctx, q.stream)
+		zcard = pipe.ZCard(ctx, q.delayed)
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("queue: %w", err)
+	}
+	return xlen.Val(), zcard.Val(), nil
+}
+
+// DeadLetters returns up to count jobs of the dead-letter stream, oldest first.
+// Malformed jobs are returned with the fields that could be read.
+func (q *Queue[T]) DeadLetters(ctx context.Context, count int64) ([]DeadLetter[T], error) {
+	ctx, cancel := q.redisCache.ReadContext(ctx)
+	defer cancel()
+
+	msgs, err := q.redisCache.Universal.XRangeN(ctx, q.dead, "-", "+", count).Result()
+	if err != nil {
+		return nil, fmt.Errorf("queue: %w", err)
+	}
+
+	letters := make([]DeadLetter[T], 0, len(msgs))
+	for _, msg := range msgs {
+		job, _ := q.decodeJob(msg)
+		errMsg, _ := msg.Values["error"].(string)
+		letters = append(letters, DeadLetter[T]{Job: job, EntryID: msg.ID, Error: errMsg})
+	}
+	return letters, nil
+}
+
+// Requeue moves the dead letter with the given entry ID back to the queue for MaxAttempts new attempts.
+// It returns false when the dead letter does not exist.
+func (q *Queue[T]) Requeue(ctx context.Context, entryID string) (bool, error) {
+	readCtx, cancelRead := q.redisCache.ReadContext(ctx)
+	defer cancelRead()
+
+	msgs, err := q.redisCache.Universal.XRangeN(readCtx, q.dead, entryID, entryID, 1).Result()
+	if err != nil {
+		return false, fmt.Errorf("queue: %w", err)
+	}
+	if len(msgs) == 0 {
+		return false, nil
+	}
+
+	id, _ := msgs[0].Values["id"].(string)
+	payload, _ := msgs[0].Values["payload"].(string)
+	writeCtx, cancelWrite := q.redisCache.WriteContext(ctx)
+	defer cancelWrite()
+
+	_, err = q.redisCache.Universal.TxPipelined(writeCtx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(writeCtx, q.entry(id, 1, []byte(payload)))
+		pipe.XDel(writeCtx, q.dead, entryID)
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("queue: %w", err)
+	}
+	return true, nil
+}
+
+func (q *Queue[T]) entry(id string, attempt int, payload []byte) *redis.XAddArgs {
+	return &redis.XAddArgs{
+		Stream: q.stream,
+		Values: []any{"id", id, "attempt", attempt, "payload", payload},
+	}
+}
+
+func delayedMember(id string, attempt int, at time.Time, payload []byte) redis.Z {
+	member := id + " " + strconv.Itoa(attempt) + " " + string(payload)
+	return redis.Z{Score: float64(at.UnixMilli()), Member: member}
+}
+
+func (q *Queue[T]) decodeJob(msg redis.XMessage) (Job[T], error) {
+	job := Job[T]{}
+	job.ID, _ = msg.Values["id"].(string)
+	attempt, _ := msg.Values["attempt"].(string)
+	payload, ok := msg.Values["payload"].(string)
+	if job.ID == "" || !ok {
+		return job, fmt.Errorf("queue: malformed entry %s", msg.ID)
+	}
+
+	var err error
+	if job.Attempt, err = strconv.Atoi(attempt); err != nil {
+		return job, fmt.Errorf("queue: malformed entry %s: %w", msg.ID, err)
+	}
+	if err := q.redisCache.Decode([]byte(payload), &job.Payload); err != nil {
+		return job, fmt.Errorf("queue: decode entry %s: %w", msg.ID, err)
+	}
+	return job, nil
+}
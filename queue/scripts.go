@@ -0,0 +1,32 @@
+package queue
+
+import "github.com/redis/go-redis/v9"
+
+// Delayed jobs are stored in a sorted set scored by the unix time in milliseconds they become due.
+// Members are "<job id> <attempt> <payload>", the job id being a 36 character UUID.
+
+// moveDueScript moves up to ARGV[2] jobs due at ARGV[1] from the delayed set KEYS[1] to the stream KEYS[2].
+// It returns the number of moved jobs.
+var moveDueScript = redis.NewScript(`
+local due = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, tonumber(ARGV[2]))
+for _, member in ipairs(due) do
+	local id = string.sub(member, 1, 36)
+	local rest = string.sub(member, 38)
+	local sep = string.find(rest, " ", 1, true)
+	redis.call("XADD", KEYS[2], "*", "id", id, "attempt", string.sub(rest, 1, sep - 1), "payload", string.sub(rest, sep + 1))
+	redis.call("ZREM", KEYS[1], member)
+end
+return #due
+`)
+
+// touchScript resets the idle time of the pending entry ARGV[2] of the stream KEYS[1] in group ARGV[1]
+// when it is still owned by consumer ARGV[3]. It returns 0 when the entry was acknowledged or claimed
+// by another consumer, without taking it back.
+var touchScript = redis.NewScript(`
+local pending = redis.call("XPENDING", KEYS[1], ARGV[1], ARGV[2], ARGV[2], 1)
+if #pending == 0 or pending[1][2] ~= ARGV[3] then
+	return 0
+end
+redis.call("XCLAIM", KEYS[1], ARGV[1], ARGV[3], 0, ARGV[2], "JUSTID")
+return 1
+`)
@@ -0,0 +1,144 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/thanvuc/go-core-lib/cache"
+)
+
+// Limit allows Rate requests per Period, with bursts of up to Burst requests.
+// Burst defaults to Rate and is ignored by the sliding window limiter.
+type Limit struct {
+	Rate   int
+	Period time.Duration
+	Burst  int
+}
+
+func PerSecond(rate int) Limit { return Limit{Rate: rate, Period: time.Second} }
+func PerMinute(rate int) Limit { return Limit{Rate: rate, Period: time.Minute} }
+func PerHour(rate int) Limit   { return Limit{Rate: rate, Period: time.Hour} }
+
+// Result describes the outcome of a rate limit check.
+type Result struct {
+	Allowed bool
+	// Limit is the number of requests allowed per period.
+	Limit int
+	// Remaining is the number of requests that can still be made right now.
+	Remaining int
+	// RetryAfter is how long to wait before the denied request would be allowed.
+	RetryAfter time.Duration
+	// ResetAfter is how long until the limit is fully available again.
+	ResetAfter time.Duration
+}
+
+// ErrInvalidN is returned by AllowN when n is not positive or exceeds the limit, so that it could never be allowed.
+var ErrInvalidN = errors.New("ratelimit: n must be between 1 and the limit")
+
+// Limiter decides whether requests identified by a key are allowed.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+	AllowN(ctx context.Context, key string, n int) (Result, error)
+}
+
+type algorithm string
+
+const (
+	tokenBucket   algorithm = "token_bucket"
+	slidingWindow algorithm = "sliding_window"
+	gcra          algorithm = "gcra"
+)
+
+type redisLimiter struct {
+	redisCache *cache.RedisCache
+	limit      Limit
+	algorithm  algorithm
+	script     *redis.Script
+	prefix     string
+}
+
+// NewTokenBucket creates a limiter that refills Rate tokens per Period into a bucket of Burst tokens.
+func NewTokenBucket(redisCache *cache.RedisCache, limit Limit) (Limiter, error) {
+	return newRedisLimiter(redisCache, limit, tokenBucket, tokenBucketScript)
+}
+
+// NewSlidingWindow creates a limiter that keeps a log of the requests made during the last Period.
+// It is exact but stores one entry per request, prefer it for low rates such as login attempts.
+func NewSlidingWindow(redisCache *cache.RedisCache, limit Limit) (Limiter, error) {
+	return newRedisLimiter(redisCache, limit, slidingWindow, slidingWindowScript)
+}
+
+// NewGCRA creates a limiter using the generic cell rate algorithm, which spaces requests
+// evenly and stores a single timestamp per key.
+func NewGCRA(redisCache *cache.RedisCache, limit Limit) (Limiter, error) {
+	return newRedisLimiter(redisCache, limit, gcra, gcraScript)
+}
+
+func newRedisLimiter(redisCache *cache.RedisCache, limit Limit, alg algorithm, script *redis.Script) (Limiter, error) {
+	if limit.Rate <= 0 {
+		return nil, errors.New("ratelimit: rate must be positive")
+	}
+	// The scripts work in milliseconds, a shorter period would divide by zero.
+	if limit.Period < time.Millisecond {
+		return nil, errors.New("ratelimit: period must be at least 1ms")
+	}
+	if limit.Burst <= 0 {
+		limit.Burst = limit.Rate
+	}
+	return &redisLimiter{
+		redisCache: redisCache,
+		limit:      limit,
+		algorithm:  alg,
+		script:     script,
+		prefix:     "ratelimit:" + string(alg) + ":",
+	}, nil
+}
+
+func (l *redisLimiter) Allow(ctx context.Context, key string) (Result, error) {
+	return l.AllowN(ctx, key, 1)
+}
+
+func (l *redisLimiter) AllowN(ctx context.Context, key string, n int) (Result, error) {
+	limit := l.limit.Burst
+	if l.algorithm == slidingWindow {
+		limit = l.limit.Rate
+	}
+	if n <= 0 || n > limit {
+		return Result{}, ErrInvalidN
+	}
+
+	periodMs := float64(l.limit.Period.Milliseconds())
+
+	var args []any
+	switch l.algorithm {
+	case tokenBucket:
+		args = []any{l.limit.Burst, float64(l.limit.Rate) / periodMs, n}
+	case slidingWindow:
+		args = []any{l.limit.Period.Milliseconds(), l.limit.Rate, n, uuid.NewString()}
+	case gcra:
+		args = []any{periodMs / float64(l.limit.Rate), l.limit.Burst, n}
+	}
+
+	ctx, cancel := l.redisCache.WriteContext(ctx)
+	defer cancel()
+
+	values, err := l.script.Run(ctx, l.redisCache.Universal, []string{l.redisCache.NamespaceKey(l.prefix + key)}, args...).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("ratelimit: %w", err)
+	}
+	if len(values) != 4 {
+		return Result{}, fmt.Errorf("ratelimit: unexpected script result %v", values)
+	}
+
+	return Result{
+		Allowed:    values[0] == 1,
+		Limit:      limit,
+		Remaining:  int(max(values[1], 0)),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		ResetAfter: time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeyFunc extracts the rate limit key from a request.
+// Requests for which it returns an empty key are not limited.
+type KeyFunc func(r *http.Request) string
+
+// KeyByIP keys requests on the client IP. When trustProxy is set the first address of the
+// X-Forwarded-For header is used, only enable it behind a proxy that overwrites that header.
+func KeyByIP(trustProxy bool) KeyFunc {
+	return func(r *http.Request) string {
+		if trustProxy {
+			if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+				ip, _, _ := strings.Cut(forwarded, ",")
+				return "ip:" + strings.TrimSpace(ip)
+			}
+		}
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		return "ip:" + host
+	}
+}
+
+// KeyByUserID keys requests on the user ID returned by userID, typically read from the request context.
+func KeyByUserID(userID func(r *http.Request) string) KeyFunc {
+	return func(r *http.Request) string {
+		if id := userID(r); id != "" {
+			return "user:" + id
+		}
+		return ""
+	}
+}
+
+// KeyByAPIKey keys requests on the API key sent in the given header.
+func KeyByAPIKey(header string) KeyFunc {
+	return func(r *http.Request) string {
+		if key := r.Header.Get(header); key != "" {
+			return "apikey:" + key
+		}
+		return ""
+	}
+}
+
+type MiddlewareOption func(*middlewareOptions)
+
+type middlewareOptions struct {
+	deniedHandler http.Handler
+	failClosed    bool
+	onError       func(r *http.Request, err error)
+}
+
+// WithDeniedHandler replaces the default 429 response.
+func WithDeniedHandler(h http.Handler) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.deniedHandler = h
+	}
+}
+
+// WithFailClosed rejects requests with 503 when the limiter fails instead of letting them through.
+func WithFailClosed() MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.failClosed = true
+	}
+}
+
+// WithErrorHook is called when the limiter fails, for example to log the error.
+func WithErrorHook(fn func(r *http.Request, err error)) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.onError = fn
+	}
+}
+
+// Middleware limits requests per key and reports the limit with the RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers, plus Retry-After on denied requests.
+func Middleware(limiter Limiter, keyFunc KeyFunc, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	o := middlewareOptions{
+		deniedHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		}),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := keyFunc(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			res, err := limiter.Allow(r.Context(), key)
+			if err != nil {
+				if o.onError != nil {
+					o.onError(r, err)
+				}
+				if o.failClosed {
+					http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(seconds(res.ResetAfter)))
+
+			if !res.Allowed {
+				if res.RetryAfter >= 0 {
+					h.Set("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+				}
+				o.deniedHandler.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// seconds rounds a duration up to whole seconds as the rate limit headers require.
+func seconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,106 @@
+package ratelimit
+
+import "github.com/redis/go-redis/v9"
+
+// The scripts read the clock from Redis so that replicas with skewed clocks share one timeline.
+// They all return {allowed, remaining, retry_after_ms, reset_after_ms}.
+
+// tokenBucketScript: ARGV = capacity, refill rate in tokens per millisecond, requested tokens.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local requested = tonumber(ARGV[3])
+
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local retry_after = 0
+if tokens >= requested then
+	tokens = tokens - requested
+	allowed = 1
+elseif requested > capacity then
+	retry_after = -1
+else
+	retry_after = math.ceil((requested - tokens) / rate)
+end
+
+redis.call("HSET", KEYS[1], "tokens", tokens, "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(capacity / rate))
+
+local reset_after = math.ceil((capacity - tokens) / rate)
+return {allowed, math.floor(tokens), retry_after, reset_after}
+`)
+
+// slidingWindowScript: ARGV = window in milliseconds, limit, requested, unique request id.
+var slidingWindowScript = redis.NewScript(`
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local requested = tonumber(ARGV[3])
+
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", now - window)
+local count = redis.call("ZCARD", KEYS[1])
+
+local allowed = 0
+local retry_after = 0
+if count + requested <= limit then
+	for i = 1, requested do
+		redis.call("ZADD", KEYS[1], now, ARGV[4] .. ":" .. i)
+	end
+	count = count + requested
+	allowed = 1
+elseif requested > limit then
+	retry_after = -1
+else
+	-- Wait until enough of the oldest requests leave the window.
+	local entry = redis.call("ZRANGE", KEYS[1], count + requested - limit - 1, count + requested - limit - 1, "WITHSCORES")
+	retry_after = tonumber(entry[2]) + window - now
+end
+
+redis.call("PEXPIRE", KEYS[1], window)
+
+local reset_after = 0
+local oldest = redis.call("ZRANGE", KEYS[1], 0, 0, "WITHSCORES")
+if oldest[2] then
+	reset_after = tonumber(oldest[2]) + window - now
+end
+return {allowed, limit - count, retry_after, reset_after}
+`)
+
+// gcraScript: ARGV = emission interval in milliseconds, burst, requested.
+var gcraScript = redis.NewScript(`
+local emission = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local requested = tonumber(ARGV[3])
+local tolerance = emission * burst
+
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + tonumber(t[2]) / 1000
+
+local tat = tonumber(redis.call("GET", KEYS[1])) or now
+tat = math.max(tat, now)
+
+local new_tat = tat + emission * requested
+local diff = now - (new_tat - tolerance)
+
+if diff < 0 then
+	local retry_after = math.ceil(-diff)
+	if requested > burst then
+		retry_after = -1
+	end
+	local remaining = math.floor((now - (tat - tolerance)) / emission)
+	return {0, remaining, retry_after, math.ceil(tat - now)}
+end
+
+local ttl = math.ceil(new_tat - now)
+redis.call("SET", KEYS[1], string.format("%.3f", new_tat), "PX", ttl)
+return {1, math.floor(diff / emission), 0, ttl}
+`)
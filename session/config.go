@@ -0,0 +1,48 @@
+package session
+
+import (
+	"net/http"
+	"time"
+)
+
+type Config struct {
+	// KeyPrefix is prepended to the Redis keys of sessions and user indexes.
+	KeyPrefix string
+	// IdleTimeout ends a session that was not used for that long. Every use extends it.
+	IdleTimeout time.Duration
+	// AbsoluteTimeout ends a session that long after it was created, however often it is used.
+	AbsoluteTimeout time.Duration
+
+	// SigningKeys sign the session cookie with HMAC-SHA256. The first key signs new cookies,
+	// all of them are accepted, so that keys can be rotated. At least one is required.
+	SigningKeys []string
+	CookieName  string
+	CookiePath  string
+	// CookieDomain is empty by default, restricting the cookie to the host that set it.
+	CookieDomain string
+	// InsecureCookie allows the cookie over plain HTTP, for local development only.
+	InsecureCookie bool
+	SameSite       http.SameSite
+}
+
+// withDefaults fills in missing config fields with sane defaults.
+func withDefaults(cfg *Config) {
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = "session"
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = 30 * time.Minute
+	}
+	if cfg.AbsoluteTimeout <= 0 {
+		cfg.AbsoluteTimeout = 24 * time.Hour
+	}
+	if cfg.CookieName == "" {
+		cfg.CookieName = "session_id"
+	}
+	if cfg.CookiePath == "" {
+		cfg.CookiePath = "/"
+	}
+	if cfg.SameSite == 0 {
+		cfg.SameSite = http.SameSiteLaxMode
+	}
+}
@@ -0,0 +1,67 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// sign returns the cookie value for a session ID: the ID and its HMAC, separated by a dot.
+func (s *Store[T]) sign(id string) string {
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac(s.keys[0], id))
+}
+
+// verify returns the session ID of a cookie value signed with any of the keys.
+func (s *Store[T]) verify(value string) (string, bool) {
+	id, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return "", false
+	}
+	sum, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return "", false
+	}
+	for _, key := range s.keys {
+		if hmac.Equal(sum, mac(key, id)) {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+func mac(key []byte, id string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(id))
+	return h.Sum(nil)
+}
+
+// setCookie sends the signed cookie of sess, expiring with its absolute timeout.
+func (s *Store[T]) setCookie(w http.ResponseWriter, sess *Session[T]) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     s.cfg.CookieName,
+		Value:    s.sign(sess.ID),
+		Path:     s.cfg.CookiePath,
+		Domain:   s.cfg.CookieDomain,
+		Expires:  sess.ExpiresAt,
+		Secure:   !s.cfg.InsecureCookie,
+		HttpOnly: true,
+		SameSite: s.cfg.SameSite,
+	})
+}
+
+func (s *Store[T]) clearCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     s.cfg.CookieName,
+		Value:    "",
+		Path:     s.cfg.CookiePath,
+		Domain:   s.cfg.CookieDomain,
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		Secure:   !s.cfg.InsecureCookie,
+		HttpOnly: true,
+		SameSite: s.cfg.SameSite,
+	})
+}
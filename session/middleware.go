@@ -0,0 +1,117 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+)
+
+type MiddlewareOption func(*middlewareOptions)
+
+type middlewareOptions struct {
+	onError func(r *http.Request, err error)
+}
+
+// WithErrorHook is called when the store fails, for example to log the error.
+// The request then proceeds without a session.
+func WithErrorHook(fn func(r *http.Request, err error)) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.onError = fn
+	}
+}
+
+type contextKey struct{}
+
+// state is the session of a request, shared between the middleware and the handler.
+type state[T any] struct {
+	sess *Session[T]
+	// saved is the encoded form of sess as loaded, to detect changes of its data.
+	saved []byte
+}
+
+// Middleware loads the session named by the signed cookie of the request, extending its idle timeout,
+// and saves its data after the handler when it was modified. Handlers read it with FromContext,
+// and start or end sessions with Start and End.
+func Middleware[T any](store *Store[T], opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	o := middlewareOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			st := &state[T]{}
+			if cookie, err := r.Cookie(store.cfg.CookieName); err == nil {
+				if id, ok := store.verify(cookie.Value); ok {
+					sess, err := store.Get(r.Context(), id)
+					switch {
+					case err == nil:
+						st.sess = sess
+						st.saved, _ = store.encode(sess)
+					case errors.Is(err, ErrNotFound):
+						store.clearCookie(w)
+					case o.onError != nil:
+						o.onError(r, err)
+					}
+				}
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), contextKey{}, st))
+			next.ServeHTTP(w, r)
+
+			if st.sess == nil || st.saved == nil {
+				return
+			}
+			if encoded, err := store.encode(st.sess); err == nil && bytes.Equal(encoded, st.saved) {
+				return
+			}
+			if err := store.Save(r.Context(), st.sess); err != nil && !errors.Is(err, ErrNotFound) && o.onError != nil {
+				o.onError(r, err)
+			}
+		})
+	}
+}
+
+// FromContext returns the session of the request, or nil when there is none.
+// Changes to its data are saved by the middleware once the handler returns.
+func FromContext[T any](ctx context.Context) *Session[T] {
+	if st, ok := ctx.Value(contextKey{}).(*state[T]); ok {
+		return st.sess
+	}
+	return nil
+}
+
+// Start creates a session for userID, typically after a login, and sends its cookie.
+// Any session of the request is ended first so that a session ID is never reused across logins.
+func (s *Store[T]) Start(w http.ResponseWriter, r *http.Request, userID string, data T) (*Session[T], error) {
+	if err := s.End(w, r); err != nil {
+		return nil, err
+	}
+
+	sess, err := s.Create(r.Context(), userID, data)
+	if err != nil {
+		return nil, err
+	}
+	s.setCookie(w, sess)
+
+	if st, ok := r.Context().Value(contextKey{}).(*state[T]); ok {
+		st.sess = sess
+		st.saved, _ = s.encode(sess)
+	}
+	return sess, nil
+}
+
+// End revokes the session of the request, typically on logout, and clears its cookie.
+func (s *Store[T]) End(w http.ResponseWriter, r *http.Request) error {
+	st, _ := r.Context().Value(contextKey{}).(*state[T])
+	if st == nil || st.sess == nil {
+		return nil
+	}
+	if err := s.Delete(r.Context(), st.sess.ID); err != nil {
+		return err
+	}
+	st.sess, st.saved = nil, nil
+	s.clearCookie(w)
+	return nil
+}
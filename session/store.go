@@ -0,0 +1,318 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/thanvuc/go-core-lib/cache"
+)
+
+// ErrNotFound is returned when a session does not exist or has expired.
+var ErrNotFound = errors.New("session: not found")
+
+// Session is a user session holding data of type T.
+type Session[T any] struct {
+	ID        string
+	UserID    string
+	Data      T
+	CreatedAt time.Time
+	// ExpiresAt is the absolute expiry of the session. It ends earlier when idle for IdleTimeout.
+	ExpiresAt time.Time
+}
+
+// record is the stored form of a session.
+type record[T any] struct {
+	UserID    string
+	Data      T
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Store keeps sessions in Redis, with an index of the sessions of every user.
+type Store[T any] struct {
+	redisCache *cache.RedisCache
+	cfg        Config
+	keys       [][]byte
+}
+
+func NewStore[T any](redisCache *cache.RedisCache, cfg Config) (*Store[T], error) {
+	withDefaults(&cfg)
+	if len(cfg.SigningKeys) == 0 {
+		return nil, errors.New("session: a signing key is required")
+	}
+
+	keys := make([][]byte, len(cfg.SigningKeys))
+	for i, key := range cfg.SigningKeys {
+		if len(key) < 32 {
+			return nil, errors.New("session: signing keys must be at least 32 bytes long")
+		}
+		keys[i] = []byte(key)
+	}
+	return &Store[T]{redisCache: redisCache, cfg: cfg, keys: keys}, nil
+}
+
+// Create starts a session for userID holding data.
+func (s *Store[T]) Create(ctx context.Context, userID string, data T) (*Session[T], error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sess := &Session[T]{
+		ID:        id,
+		UserID:    userID,
+		Data:      data,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.cfg.AbsoluteTimeout),
+	}
+	encoded, err := s.encode(sess)
+	if err != nil {
+		return nil, err
+	}
+
+	// The session and the index of the user are in different slots in cluster mode, so they are not
+	// written in one transaction. The index is written first: an indexed session that was never
+	// stored is dropped by List, while a stored session missing from the index escapes RevokeAll.
+	writeCtx, cancel := s.redisCache.WriteContext(ctx)
+	defer cancel()
+
+	index := s.userKey(userID)
+	_, err = s.redisCache.Universal.TxPipelined(writeCtx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, index, redis.Z{Score: float64(sess.ExpiresAt.UnixMilli()), Member: id})
+		// The index lives as long as the last session it lists.
+		pipe.PExpireAt(ctx, index, sess.ExpiresAt)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("session: %w", err)
+	}
+	if err := s.redisCache.Universal.Set(writeCtx, s.sessionKey(id), encoded, s.ttl(sess, now)).Err(); err != nil {
+		// Best effort, List drops the entry anyway.
+		cleanupCtx, cancelCleanup := s.redisCache.WriteContext(context.WithoutCancel(ctx))
+		defer cancelCleanup()
+		s.redisCache.Universal.ZRem(cleanupCtx, index, id)
+		return nil, fmt.Errorf("session: %w", err)
+	}
+	return sess, nil
+}
+
+// Get returns the session id and extends its idle timeout. It returns ErrNotFound when it expired.
+func (s *Store[T]) Get(ctx context.Context, id string) (*Session[T], error) {
+	sess, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !now.Before(sess.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+
+	writeCtx, cancel := s.redisCache.WriteContext(ctx)
+	defer cancel()
+	if err := s.redisCache.Universal.PExpire(writeCtx, s.sessionKey(id), s.ttl(sess, now)).Err(); err != nil {
+		return nil, fmt.Errorf("session: %w", err)
+	}
+	return sess, nil
+}
+
+// Save stores the data of sess, keeping its expiry. It returns ErrNotFound when the session ended meanwhile.
+func (s *Store[T]) Save(ctx context.Context, sess *Session[T]) error {
+	encoded, err := s.encode(sess)
+	if err != nil {
+		return err
+	}
+
+	ttl := s.ttl(sess, time.Now())
+	if ttl <= 0 {
+		return ErrNotFound
+	}
+	writeCtx, cancel := s.redisCache.WriteContext(ctx)
+	defer cancel()
+
+	// XX so that a revoked session is not brought back.
+	ok, err := s.redisCache.Universal.SetXX(writeCtx, s.sessionKey(sess.ID), encoded, ttl).Result()
+	if err != nil {
+		return fmt.Errorf("session: %w", err)
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete revokes the session id.
+func (s *Store[T]) Delete(ctx context.Context, id string) error {
+	sess, err := s.load(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	writeCtx, cancel := s.redisCache.WriteContext(ctx)
+	defer cancel()
+
+	// Not a transaction, the keys are in different slots in cluster mode. A leftover index entry is
+	// dropped by List.
+	if err := s.redisCache.Universal.Del(writeCtx, s.sessionKey(id)).Err(); err != nil {
+		return fmt.Errorf("session: %w", err)
+	}
+	if err := s.redisCache.Universal.ZRem(writeCtx, s.userKey(sess.UserID), id).Err(); err != nil {
+		return fmt.Errorf("session: %w", err)
+	}
+	return nil
+}
+
+// List returns the active sessions of userID, oldest first.
+func (s *Store[T]) List(ctx context.Context, userID string) ([]*Session[T], error) {
+	ids, err := s.activeIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*Session[T], 0, len(ids))
+	var ended []any
+	for _, id := range ids {
+		sess, err := s.load(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			ended = append(ended, id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, sess)
+	}
+
+	// Sessions that ended on idle timeout are still in the index.
+	if len(ended) > 0 {
+		writeCtx, cancel := s.redisCache.WriteContext(ctx)
+		defer cancel()
+		if err := s.redisCache.Universal.ZRem(writeCtx, s.userKey(userID), ended...).Err(); err != nil {
+			return nil, fmt.Errorf("session: %w", err)
+		}
+	}
+	return sessions, nil
+}
+
+// RevokeAll ends every session of userID and returns how many were active.
+func (s *Store[T]) RevokeAll(ctx context.Context, userID string) (int, error) {
+	ids, err := s.activeIDs(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	writeCtx, cancel := s.redisCache.WriteContext(ctx)
+	defer cancel()
+
+	cmds := make([]*redis.IntCmd, len(ids))
+	_, err = s.redisCache.Universal.Pipelined(writeCtx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.Unlink(writeCtx, s.sessionKey(id))
+		}
+		pipe.Del(writeCtx, s.userKey(userID))
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("session: %w", err)
+	}
+
+	revoked := 0
+	for _, cmd := range cmds {
+		revoked += int(cmd.Val())
+	}
+	return revoked, nil
+}
+
+// activeIDs returns the IDs of the sessions of userID that did not reach their absolute expiry,
+// dropping the others from the index.
+func (s *Store[T]) activeIDs(ctx context.Context, userID string) ([]string, error) {
+	index := s.userKey(userID)
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+
+	writeCtx, cancel := s.redisCache.WriteContext(ctx)
+	defer cancel()
+
+	var ids *redis.StringSliceCmd
+	_, err := s.redisCache.Universal.TxPipelined(writeCtx, func(pipe redis.Pipeliner) error {
+		pipe.ZRemRangeByScore(writeCtx, index, "-inf", now)
+		ids = pipe.ZRange(writeCtx, index, 0, -1)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("session: %w", err)
+	}
+	return ids.Val(), nil
+}
+
+// load reads a session without extending it.
+func (s *Store[T]) load(ctx context.Context, id string) (*Session[T], error) {
+	ctx, cancel := s.redisCache.ReadContext(ctx)
+	defer cancel()
+
+	data, err := s.redisCache.Universal.Get(ctx, s.sessionKey(id)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("session: %w", err)
+	}
+	return s.decode(id, data)
+}
+
+// ttl is the time left before sess ends if not used again.
+func (s *Store[T]) ttl(sess *Session[T], now time.Time) time.Duration {
+	return min(s.cfg.IdleTimeout, sess.ExpiresAt.Sub(now))
+}
+
+func (s *Store[T]) encode(sess *Session[T]) ([]byte, error) {
+	data, err := s.redisCache.Encode(record[T]{
+		UserID:    sess.UserID,
+		Data:      sess.Data,
+		CreatedAt: sess.CreatedAt,
+		ExpiresAt: sess.ExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("session: encode: %w", err)
+	}
+	return data, nil
+}
+
+func (s *Store[T]) decode(id string, data []byte) (*Session[T], error) {
+	var rec record[T]
+	if err := s.redisCache.Decode(data, &rec); err != nil {
+		return nil, fmt.Errorf("session: decode: %w", err)
+	}
+	return &Session[T]{
+		ID:        id,
+		UserID:    rec.UserID,
+		Data:      rec.Data,
+		CreatedAt: rec.CreatedAt,
+		ExpiresAt: rec.ExpiresAt,
+	}, nil
+}
+
+func (s *Store[T]) sessionKey(id string) string {
+	return s.redisCache.NamespaceKey(s.cfg.KeyPrefix + ":" + id)
+}
+
+func (s *Store[T]) userKey(userID string) string {
+	return s.redisCache.NamespaceKey(s.cfg.KeyPrefix + ":user:" + userID)
+}
+
+// newID returns a random session ID of 256 bits.
+func newID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("session: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}